@@ -0,0 +1,142 @@
+// Command ugs-query queries a game server over the SQP or A2S query protocol and prints what it reports.
+//
+// Usage:
+//
+//	ugs-query [flags] host:port
+//
+// The flags are:
+//
+//	--protocol sqp|a2s
+//		The query protocol to use. Defaults to sqp.
+//	--format table|json
+//		The output format. Defaults to table. In JSON format, one object is printed per line.
+//	--timeout duration
+//		The time allowed for each query. Defaults to 5s.
+//	--watch duration
+//		If set, the server is queried repeatedly at this interval until interrupted.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+type (
+	// options holds the parsed command-line options.
+	options struct {
+		address  string
+		protocol string
+		format   string
+		timeout  time.Duration
+		watch    time.Duration
+	}
+)
+
+var (
+	errUsage               = errors.New("usage: ugs-query [flags] host:port")
+	errUnsupportedFormat   = errors.New("unsupported format")
+	errUnsupportedProtocol = errors.New("unsupported protocol")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+// run parses the command-line arguments and queries the server, writing the output to stdout.
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) error {
+	opts, err := parseOptions(args, stderr)
+	if err != nil {
+		return err
+	}
+
+	q, err := newQuerier(opts)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	for {
+		if err = queryAndPrint(ctx, q, opts, stdout); err != nil {
+			// Errors are reported without stopping when watching, as the server may be restarting.
+			if opts.watch == 0 || ctx.Err() != nil {
+				return err
+			}
+
+			fmt.Fprintln(stderr, err)
+		}
+
+		if opts.watch == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.watch):
+		}
+	}
+}
+
+// parseOptions parses the command-line arguments.
+func parseOptions(args []string, stderr io.Writer) (*options, error) {
+	opts := &options{}
+
+	fs := flag.NewFlagSet("ugs-query", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.protocol, "protocol", "sqp", "query protocol: sqp or a2s")
+	fs.StringVar(&opts.format, "format", "table", "output format: table or json")
+	fs.DurationVar(&opts.timeout, "timeout", 5*time.Second, "time allowed for each query")
+	fs.DurationVar(&opts.watch, "watch", 0, "query repeatedly at this interval until interrupted")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 1 {
+		return nil, errUsage
+	}
+
+	opts.address = fs.Arg(0)
+
+	if opts.format != "table" && opts.format != "json" {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedFormat, opts.format)
+	}
+
+	return opts, nil
+}
+
+// queryAndPrint queries the server once and prints the report.
+func queryAndPrint(ctx context.Context, q querier, opts *options, stdout io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	r, err := q.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", opts.address, err)
+	}
+
+	if opts.format == "json" {
+		return printJSON(stdout, r)
+	}
+
+	if opts.watch != 0 {
+		fmt.Fprintf(stdout, "# %s %s\n", opts.address, time.Now().Format(time.RFC3339))
+	}
+
+	return printTable(stdout, r)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/a2s"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+var testState = &proto.QueryState{
+	CurrentPlayers: 1,
+	MaxPlayers:     8,
+	ServerName:     "foo",
+	GameType:       "ctf",
+	Map:            "bar",
+	Version:        "1.0.0",
+	Port:           9000,
+	Metrics:        []float32{1.5},
+	Players: []proto.QueryPlayer{
+		{Name: "alice", Score: 3, Ping: 20, JoinedAt: time.Now().Add(-time.Minute)},
+	},
+	Rules: map[string]any{"mode": "ctf"},
+}
+
+// serve responds to queries on a local UDP endpoint with the provided responder, returning the endpoint address.
+func serve(t *testing.T, r proto.QueryResponder) string {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			resp, err := r.Respond(addr.String(), buf[:n])
+			if err != nil {
+				continue
+			}
+
+			if _, err = conn.WriteToUDP(resp, addr); err != nil {
+				return
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func Test_run_sqp(t *testing.T) {
+	t.Parallel()
+
+	r, err := sqp.NewQueryResponder(testState)
+	require.NoError(t, err)
+	addr := serve(t, r)
+
+	var out bytes.Buffer
+	require.NoError(t, run(context.Background(), []string{"--format", "json", addr}, &out, io.Discard))
+
+	var got report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, report{
+		Protocol: "sqp",
+		Server: serverInfo{
+			Name:       "foo",
+			Map:        "bar",
+			GameType:   "ctf",
+			Players:    1,
+			MaxPlayers: 8,
+			Port:       9000,
+		},
+		Players: []player{{Name: "alice", Score: 3, Ping: 20}},
+		Rules:   map[string]string{"mode": "ctf"},
+		Metrics: []float32{1.5},
+	}, got)
+
+	out.Reset()
+	require.NoError(t, run(context.Background(), []string{addr}, &out, io.Discard))
+	require.Contains(t, out.String(), "Players    1/8\n")
+	require.Contains(t, out.String(), "alice   3      20\n")
+	require.Contains(t, out.String(), "0       1.5\n")
+}
+
+func Test_run_a2s(t *testing.T) {
+	t.Parallel()
+
+	r, err := a2s.NewQueryResponder(testState)
+	require.NoError(t, err)
+	addr := serve(t, r)
+
+	var out bytes.Buffer
+	require.NoError(t, run(context.Background(), []string{"--protocol", "a2s", "--format", "json", addr}, &out, io.Discard))
+
+	var got report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Equal(t, "a2s", got.Protocol)
+	require.Equal(t, "1.0.0", got.Server.Version)
+	require.Equal(t, map[string]string{"mode": "ctf"}, got.Rules)
+	require.Len(t, got.Players, 1)
+	require.Equal(t, "alice", got.Players[0].Name)
+	require.InDelta(t, 60, got.Players[0].DurationSeconds, 5)
+
+	out.Reset()
+	require.NoError(t, run(context.Background(), []string{"--protocol", "a2s", addr}, &out, io.Discard))
+	require.Contains(t, out.String(), "RULE  VALUE\nmode  ctf\n")
+}
+
+func Test_run_watch(t *testing.T) {
+	t.Parallel()
+
+	r, err := sqp.NewQueryResponder(testState)
+	require.NoError(t, err)
+	addr := serve(t, r)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	var out bytes.Buffer
+	require.NoError(t, run(ctx, []string{"--format", "json", "--watch", "50ms", addr}, &out, io.Discard))
+	require.Greater(t, bytes.Count(out.Bytes(), []byte("\n")), 1)
+}
+
+func Test_parseOptions_invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := parseOptions(nil, io.Discard)
+	require.ErrorIs(t, err, errUsage)
+
+	_, err = parseOptions([]string{"--format", "xml", "localhost:9000"}, io.Discard)
+	require.ErrorIs(t, err, errUnsupportedFormat)
+
+	err = run(context.Background(), []string{"--protocol", "q3", "localhost:9000"}, io.Discard, io.Discard)
+	require.ErrorIs(t, err, errUnsupportedProtocol)
+}
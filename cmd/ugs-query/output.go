@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// printJSON prints the report as a single line of JSON.
+func printJSON(w io.Writer, r *report) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// printTable prints the report as aligned tables of server info, players, rules and metrics.
+func printTable(w io.Writer, r *report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Protocol\t%s\n", r.Protocol)
+	fmt.Fprintf(tw, "Name\t%s\n", r.Server.Name)
+	fmt.Fprintf(tw, "Map\t%s\n", r.Server.Map)
+	fmt.Fprintf(tw, "Game Type\t%s\n", r.Server.GameType)
+	fmt.Fprintf(tw, "Version\t%s\n", r.Server.Version)
+	fmt.Fprintf(tw, "Players\t%d/%d\n", r.Server.Players, r.Server.MaxPlayers)
+	fmt.Fprintf(tw, "Port\t%d\n", r.Server.Port)
+
+	if len(r.Players) > 0 {
+		fmt.Fprintln(tw)
+		if r.Protocol == "a2s" {
+			fmt.Fprintln(tw, "PLAYER\tSCORE\tDURATION")
+			for _, p := range r.Players {
+				fmt.Fprintf(tw, "%s\t%d\t%.0fs\n", p.Name, p.Score, p.DurationSeconds)
+			}
+		} else {
+			fmt.Fprintln(tw, "PLAYER\tSCORE\tPING")
+			for _, p := range r.Players {
+				fmt.Fprintf(tw, "%s\t%d\t%d\n", p.Name, p.Score, p.Ping)
+			}
+		}
+	}
+
+	if len(r.Rules) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "RULE\tVALUE")
+		for _, k := range sortedKeys(r.Rules) {
+			fmt.Fprintf(tw, "%s\t%s\n", k, r.Rules[k])
+		}
+	}
+
+	if len(r.Metrics) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "METRIC\tVALUE")
+		for i, v := range r.Metrics {
+			fmt.Fprintf(tw, "%d\t%g\n", i, v)
+		}
+	}
+
+	return tw.Flush()
+}
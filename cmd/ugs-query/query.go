@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	a2sclient "github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/a2s/client"
+	sqpclient "github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp/client"
+)
+
+type (
+	// querier queries a server, returning a protocol-independent report.
+	querier interface {
+		Query(ctx context.Context) (*report, error)
+		Close() error
+	}
+
+	// report holds what a server reports in response to queries.
+	report struct {
+		Protocol string            `json:"protocol"`
+		Server   serverInfo        `json:"server"`
+		Players  []player          `json:"players"`
+		Rules    map[string]string `json:"rules"`
+		Metrics  []float32         `json:"metrics,omitempty"`
+	}
+
+	// serverInfo holds the server information reported by a server.
+	serverInfo struct {
+		Name       string `json:"name"`
+		Map        string `json:"map"`
+		GameType   string `json:"gameType"`
+		Version    string `json:"version,omitempty"`
+		Players    int    `json:"players"`
+		MaxPlayers int    `json:"maxPlayers"`
+		Port       uint16 `json:"port,omitempty"`
+	}
+
+	// player holds a single player reported by a server. Ping is only reported by SQP and duration only by A2S.
+	player struct {
+		Name            string  `json:"name"`
+		Score           int32   `json:"score"`
+		Ping            uint16  `json:"ping,omitempty"`
+		DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	}
+
+	// sqpQuerier queries a server using the SQP protocol.
+	sqpQuerier struct {
+		*sqpclient.Client
+	}
+
+	// a2sQuerier queries a server using the A2S protocol.
+	a2sQuerier struct {
+		*a2sclient.Client
+	}
+)
+
+// newQuerier creates a querier for the protocol in the provided options.
+func newQuerier(opts *options) (querier, error) {
+	switch opts.protocol {
+	case "sqp":
+		c, err := sqpclient.New(opts.address, sqpclient.WithTimeout(opts.timeout))
+		if err != nil {
+			return nil, err
+		}
+
+		return &sqpQuerier{Client: c}, nil
+
+	case "a2s":
+		c, err := a2sclient.New(opts.address, a2sclient.WithTimeout(opts.timeout))
+		if err != nil {
+			return nil, err
+		}
+
+		return &a2sQuerier{Client: c}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", errUnsupportedProtocol, opts.protocol)
+}
+
+// Query queries every chunk from the server.
+func (q *sqpQuerier) Query(ctx context.Context) (*report, error) {
+	resp, err := q.Client.Query(ctx, sqpclient.ChunkAll)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &report{
+		Protocol: "sqp",
+		Players:  make([]player, 0, len(resp.Players)),
+		Rules:    make(map[string]string, len(resp.Rules)),
+		Metrics:  resp.Metrics,
+	}
+
+	if si := resp.ServerInfo; si != nil {
+		r.Server = serverInfo{
+			Name:       si.ServerName,
+			Map:        si.GameMap,
+			GameType:   si.GameType,
+			Version:    si.BuildID,
+			Players:    int(si.CurrentPlayers),
+			MaxPlayers: int(si.MaxPlayers),
+			Port:       si.Port,
+		}
+	}
+
+	for _, p := range resp.Players {
+		r.Players = append(r.Players, player{Name: p.Name, Score: p.Score, Ping: p.Ping})
+	}
+
+	for k, v := range resp.Rules {
+		r.Rules[k] = fmt.Sprint(v)
+	}
+
+	return r, nil
+}
+
+// Query performs A2S_INFO, A2S_PLAYER and A2S_RULES queries.
+func (q *a2sQuerier) Query(ctx context.Context) (*report, error) {
+	info, err := q.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := q.Players(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := q.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &report{
+		Protocol: "a2s",
+		Server: serverInfo{
+			Name:       info.ServerName,
+			Map:        info.Map,
+			GameType:   info.Game,
+			Version:    info.Version,
+			Players:    int(info.Players),
+			MaxPlayers: int(info.MaxPlayers),
+			Port:       info.Port,
+		},
+		Players: make([]player, 0, len(players)),
+		Rules:   rules,
+	}
+
+	for _, p := range players {
+		r.Players = append(r.Players, player{Name: p.Name, Score: p.Score, DurationSeconds: p.Duration.Seconds()})
+	}
+
+	return r, nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
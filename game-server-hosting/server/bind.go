@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net"
@@ -20,18 +21,25 @@ type (
 // errBindingClosed is an error representing that the UDP binding is closed.
 var errBindingClosed = errors.New("binding is closed")
 
-// newUDPBinding creates a new UDP binding on the specified address.
-func newUDPBinding(bindAddress string, readBufferSizeBytes int, writeBufferSizeBytes int, readDeadlineDuration time.Duration, writeDeadlineDuration time.Duration) (*udpBinding, error) {
-	address, err := net.ResolveUDPAddr("udp4", bindAddress)
-	if err != nil {
-		return nil, err
+// newUDPBinding creates a new UDP binding on the specified network ("udp4", "udp6" or "udp" for dual-stack) and address.
+// If reusePort is set, the socket is bound with SO_REUSEPORT so that other sockets can bind to the same address.
+func newUDPBinding(network string, bindAddress string, reusePort bool, readBufferSizeBytes int, writeBufferSizeBytes int, readDeadlineDuration time.Duration, writeDeadlineDuration time.Duration) (*udpBinding, error) {
+	var lc net.ListenConfig
+	if reusePort {
+		lc.Control = reusePortControl
 	}
 
-	conn, err := net.ListenUDP("udp4", address)
+	pc, err := lc.ListenPacket(context.Background(), network, bindAddress)
 	if err != nil {
 		return nil, err
 	}
 
+	conn, ok := pc.(*net.UDPConn)
+	if !ok {
+		pc.Close()
+		return nil, fmt.Errorf("unexpected connection type %T", pc)
+	}
+
 	if err = conn.SetReadBuffer(readBufferSizeBytes); err != nil {
 		return nil, fmt.Errorf("error setting read buffer: %w", err)
 	}
@@ -82,7 +90,15 @@ func (b *udpBinding) Close() {
 
 	close(b.done)
 	b.conn.Close()
-	b.conn = nil
+}
+
+// Interrupt interrupts a blocked Read, which returns a timeout error.
+func (b *udpBinding) Interrupt() {
+	if b.IsDone() {
+		return
+	}
+
+	_ = b.conn.SetReadDeadline(time.Now())
 }
 
 // IsDone determines whether the binding is complete.
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"net"
 	"testing"
 	"time"
@@ -10,7 +11,7 @@ import (
 
 func Test_BindLifecycle(t *testing.T) {
 	t.Parallel()
-	b, err := newUDPBinding(":0", 128, 128, 1*time.Second, 1*time.Second)
+	b, err := newUDPBinding("udp4", ":0", false, 128, 128, 1*time.Second, 1*time.Second)
 	require.NoError(t, err)
 	require.NotNil(t, b)
 	require.False(t, b.IsDone())
@@ -32,3 +33,44 @@ func Test_BindLifecycle(t *testing.T) {
 	b.Close()
 	require.True(t, b.IsDone())
 }
+
+func Test_BindIPv6(t *testing.T) {
+	t.Parallel()
+	b, err := newUDPBinding("udp6", "[::1]:0", false, 128, 128, 1*time.Second, 1*time.Second)
+	if err != nil {
+		t.Skipf("IPv6 unavailable: %s", err)
+	}
+	defer b.Close()
+
+	client, err := net.DialUDP("udp6", nil, b.conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+
+	expected := []byte("hello bind")
+	_, err = client.Write(expected)
+	require.NoError(t, err)
+
+	actual := make([]byte, len(expected))
+	_, from, err := b.Read(actual)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+	require.Nil(t, from.IP.To4())
+}
+
+func Test_BindReusePort(t *testing.T) {
+	t.Parallel()
+	first, err := newUDPBinding("udp4", "127.0.0.1:0", true, 128, 128, 1*time.Second, 1*time.Second)
+	if errors.Is(err, ErrReusePortUnsupported) {
+		t.Skip("SO_REUSEPORT unsupported")
+	}
+	require.NoError(t, err)
+	defer first.Close()
+
+	// A second socket can bind to the same address.
+	second, err := newUDPBinding("udp4", first.conn.LocalAddr().String(), true, 128, 128, 1*time.Second, 1*time.Second)
+	require.NoError(t, err)
+	second.Close()
+
+	// Sockets without SO_REUSEPORT cannot.
+	_, err = newUDPBinding("udp4", first.conn.LocalAddr().String(), false, 128, 128, 1*time.Second, 1*time.Second)
+	require.Error(t, err)
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"reflect"
 )
@@ -54,31 +56,149 @@ type (
 		// Extra represents any other arguments passed to this server, for example, those specified in a build configuration.
 		Extra map[string]string `json:"-"`
 	}
+
+	// ConfigFieldError represents a problem with the value of a single configuration field.
+	ConfigFieldError struct {
+		// Field is the JSON name of the field.
+		Field string
+
+		// Err is the problem with the field, for example ErrConfigFieldRequired.
+		Err error
+	}
+)
+
+var (
+	// ErrConfigFieldRequired represents that a required configuration field is missing.
+	ErrConfigFieldRequired = errors.New("field is required")
+
+	// ErrConfigFieldNotNumeric represents that a configuration field which must be an integer is not.
+	ErrConfigFieldNotNumeric = errors.New("field is not an integer")
+
+	// ErrConfigPortOutOfRange represents that a configuration field which must be a port number is outside the
+	// range 1-65535.
+	ErrConfigPortOutOfRange = errors.New("port is out of range")
+
+	// ErrConfigEmpty represents that the configuration file holds no configuration.
+	ErrConfigEmpty = errors.New("configuration is empty")
+
+	// ErrConfigInvalidURL represents that a configuration field which must be an absolute URL is not.
+	ErrConfigInvalidURL = errors.New("field is not a valid URL")
 )
 
+// Error returns the field name and problem.
+func (e *ConfigFieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+// Unwrap returns the problem with the field.
+func (e *ConfigFieldError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the configuration, returning an error joining a *ConfigFieldError for each field with a problem,
+// or nil if the configuration is valid. The query port, server ID and server log directory are required, ports must
+// be in the range 1-65535, IDs must be integers, the local proxy URL must be absolute and the query type must be a
+// registered query protocol.
+func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+// validateChange checks configuration which replaces the configuration loaded by Start. Fields which are only
+// required by Start may be omitted, as they are when Multiplay writes the minimal configuration for a deallocation,
+// but any fields which are present are checked as per Validate.
+func (c *Config) validateChange() error {
+	return c.validate(false)
+}
+
+// validate checks the configuration as per Validate, only requiring fields if required is true.
+func (c *Config) validate(required bool) error {
+	var errs []error
+	fieldErr := func(field string, err error) {
+		errs = append(errs, &ConfigFieldError{Field: field, Err: err})
+	}
+
+	validatePort := func(field string, port json.Number, required bool) {
+		if port == "" {
+			if required {
+				fieldErr(field, ErrConfigFieldRequired)
+			}
+
+			return
+		}
+
+		n, err := port.Int64()
+		switch {
+		case err != nil:
+			fieldErr(field, ErrConfigFieldNotNumeric)
+		case n < 1 || n > 65535:
+			fieldErr(field, ErrConfigPortOutOfRange)
+		}
+	}
+
+	validateID := func(field string, id json.Number, required bool) {
+		if id == "" {
+			if required {
+				fieldErr(field, ErrConfigFieldRequired)
+			}
+
+			return
+		}
+
+		if _, err := id.Int64(); err != nil {
+			fieldErr(field, ErrConfigFieldNotNumeric)
+		}
+	}
+
+	validatePort("queryPort", c.QueryPort, required)
+	validatePort("port", c.Port, false)
+	validateID("serverID", c.ServerID, required)
+	validateID("machineID", c.MachineID, false)
+
+	if required && c.ServerLogDir == "" {
+		fieldErr("serverLogDir", ErrConfigFieldRequired)
+	}
+
+	if u, err := url.Parse(c.LocalProxyURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fieldErr("localProxyUrl", ErrConfigInvalidURL)
+	}
+
+	queryProtocolsMtx.RLock()
+	_, ok := queryProtocols[c.QueryType]
+	queryProtocolsMtx.RUnlock()
+
+	if !ok {
+		fieldErr("queryType", ErrUnsupportedQueryType)
+	}
+
+	return errors.Join(errs...)
+}
+
 // newConfigFromFile loads configuration from the specified file. The returned ServerLogDir field has its
 // value modified to include the absolute path from the current home directory.
 func newConfigFromFile(configFile string) (*Config, error) {
-	var cfg *Config
-
-	f, err := os.Open(configFile)
+	data, err := os.ReadFile(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file: %w", err)
+		return nil, fmt.Errorf("error reading file: %w", err)
 	}
 
-	defer f.Close()
+	return newConfigFromJSON(data)
+}
+
+// newConfigFromJSON loads configuration from the provided JSON document, applying defaults as per newConfigFromFile.
+func newConfigFromJSON(data []byte) (*Config, error) {
+	var cfg *Config
 
 	// Decode known fields into struct.
-	if err = json.NewDecoder(f).Decode(&cfg); err != nil {
+	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("error decoding json: %w", err)
 	}
 
-	if _, err = f.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("unable to seek: %w", err)
+	if cfg == nil {
+		return nil, fmt.Errorf("error decoding json: %w", ErrConfigEmpty)
 	}
 
 	// Decode all other fields into Extra - this can include custom Build Configuration data.
-	if err = json.NewDecoder(f).Decode(&cfg.Extra); err != nil {
+	if err := json.Unmarshal(data, &cfg.Extra); err != nil {
 		return nil, fmt.Errorf("error decoding json: %w", err)
 	}
 
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"reflect"
+	"sort"
+)
+
+type (
+	// ConfigChange represents a change of the configuration the server is using.
+	ConfigChange struct {
+		// Old is the configuration before the change. This is empty for the configuration loaded by Start.
+		Old Config
+
+		// New is the configuration after the change.
+		New Config
+
+		// Fields holds the JSON names of the changed fields, for example "queryType", in declaration order.
+		Fields []string
+
+		// ExtraKeys holds the sorted keys of the Extra values which have been added, removed or changed.
+		ExtraKeys []string
+	}
+
+	// configSubscription represents a function called when the named configuration field or Extra key changes.
+	configSubscription struct {
+		name string
+		fn   func(ConfigChange)
+	}
+)
+
+// newConfigChange creates a change between the provided configurations.
+func newConfigChange(old Config, new Config) ConfigChange {
+	change := ConfigChange{
+		Old: old,
+		New: new,
+	}
+
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < ov.NumField(); i++ {
+		name := ov.Type().Field(i).Tag.Get("json")
+		if name == "-" {
+			continue
+		}
+
+		if ov.Field(i).Interface() != nv.Field(i).Interface() {
+			change.Fields = append(change.Fields, name)
+		}
+	}
+
+	for k, v := range new.Extra {
+		if ov, ok := old.Extra[k]; !ok || ov != v {
+			change.ExtraKeys = append(change.ExtraKeys, k)
+		}
+	}
+
+	for k := range old.Extra {
+		if _, ok := new.Extra[k]; !ok {
+			change.ExtraKeys = append(change.ExtraKeys, k)
+		}
+	}
+
+	sort.Strings(change.ExtraKeys)
+
+	return change
+}
+
+// Changed determines whether the configuration field with the provided JSON name, or the Extra value with the
+// provided key, has changed.
+func (c ConfigChange) Changed(name string) bool {
+	for _, f := range c.Fields {
+		if f == name {
+			return true
+		}
+	}
+
+	for _, k := range c.ExtraKeys {
+		if k == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Empty determines whether nothing has changed.
+func (c ConfigChange) Empty() bool {
+	return len(c.Fields) == 0 && len(c.ExtraKeys) == 0
+}
+
+// OnConfigChange returns a read-only channel that receives a message describing each change of the configuration,
+// including the configuration loaded by Start.
+func (s *Server) OnConfigChange() <-chan ConfigChange {
+	return s.chanConfigChange
+}
+
+// OnConfigFieldChanged registers fn to be called whenever the configuration field with the provided JSON name, for
+// example "queryType", or the Extra value with the provided key changes. Functions are called in the order they
+// were registered, on the goroutine which detected the change, so must not block.
+func (s *Server) OnConfigFieldChanged(name string, fn func(ConfigChange)) {
+	s.configSubscriptionsMtx.Lock()
+	defer s.configSubscriptionsMtx.Unlock()
+
+	s.configSubscriptions = append(s.configSubscriptions, configSubscription{name: name, fn: fn})
+}
+
+// publishConfigChange propagates a configuration change to consumers of OnConfigChange and any subscribed functions.
+func (s *Server) publishConfigChange(change ConfigChange) {
+	if change.Empty() {
+		return
+	}
+
+	// Listening for changes is optional, so make sure we don't deadlock if nobody is listening.
+	select {
+	case s.chanConfigChange <- change:
+	default:
+	}
+
+	s.configSubscriptionsMtx.Lock()
+	subscriptions := s.configSubscriptions
+	s.configSubscriptionsMtx.Unlock()
+
+	for _, sub := range subscriptions {
+		if change.Changed(sub.name) {
+			sub.fn(change)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/internal/localproxytest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newConfigChange(t *testing.T) {
+	t.Parallel()
+
+	old := Config{
+		AllocatedUUID: "",
+		QueryPort:     "9010",
+		QueryType:     QueryProtocolSQP,
+		Extra:         map[string]string{"a": "1", "b": "2", "c": "3"},
+	}
+	next := Config{
+		AllocatedUUID: "a-uuid",
+		QueryPort:     "9010",
+		QueryType:     QueryProtocolA2S,
+		Extra:         map[string]string{"a": "1", "b": "changed", "d": "4"},
+	}
+
+	change := newConfigChange(old, next)
+	require.Equal(t, old, change.Old)
+	require.Equal(t, next, change.New)
+	require.Equal(t, []string{"allocatedUUID", "queryType"}, change.Fields)
+	require.Equal(t, []string{"b", "c", "d"}, change.ExtraKeys)
+	require.True(t, change.Changed("queryType"))
+	require.True(t, change.Changed("d"))
+	require.False(t, change.Changed("queryPort"))
+	require.False(t, change.Changed("a"))
+	require.False(t, change.Empty())
+
+	require.True(t, newConfigChange(old, old).Empty())
+}
+
+func Test_OnConfigFieldChanged(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+
+	var queryTypes []QueryProtocol
+	s.OnConfigFieldChanged("queryType", func(c ConfigChange) {
+		queryTypes = append(queryTypes, c.New.QueryType)
+	})
+
+	var rounds []string
+	s.OnConfigFieldChanged("rounds", func(c ConfigChange) {
+		rounds = append(rounds, c.New.Extra["rounds"])
+	})
+
+	s.setConfig(&Config{QueryType: QueryProtocolSQP})
+	change := <-s.OnConfigChange()
+	require.Equal(t, []string{"queryType"}, change.Fields)
+
+	s.setConfig(&Config{QueryType: QueryProtocolSQP, Extra: map[string]string{"rounds": "3"}})
+	change = <-s.OnConfigChange()
+	require.Equal(t, []string{"rounds"}, change.ExtraKeys)
+
+	// Nothing changed, so nothing is published.
+	s.setConfig(&Config{QueryType: QueryProtocolSQP, Extra: map[string]string{"rounds": "3"}})
+	require.Len(t, s.OnConfigChange(), 0)
+
+	s.setConfig(&Config{QueryType: QueryProtocolA2S})
+	<-s.OnConfigChange()
+
+	require.Equal(t, []QueryProtocol{QueryProtocolSQP, QueryProtocolA2S}, queryTypes)
+	require.Equal(t, []string{"3", ""}, rounds)
+}
+
+func Test_ConfigChange_restartsQueryEndpoint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	queryEndpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	newQueryEndpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	svr, err := localproxytest.NewLocalProxy()
+	require.NoError(t, err)
+	defer svr.Close()
+
+	writeConfig := func(endpoint string, queryType QueryProtocol) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "server.json"), []byte(fmt.Sprintf(`{
+			"queryPort": "%s",
+			"queryType": "%s",
+			"serverID": "1234",
+			"serverLogDir": "%s",
+			"localProxyUrl": "%s"
+		}`, strings.Split(endpoint, ":")[1], queryType, filepath.Join(dir, "logs"), svr.Host)), 0o600))
+	}
+
+	writeConfig(queryEndpoint, QueryProtocolSQP)
+
+	s, err := New(TypeAllocation, WithConfigPath(filepath.Join(dir, "server.json")))
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	<-s.OnConfigChange()
+
+	writeConfig(newQueryEndpoint, QueryProtocolA2S)
+	change := <-s.OnConfigChange()
+	require.True(t, change.Changed("queryType"))
+	require.True(t, change.Changed("queryPort"))
+
+	// The new endpoint responds to A2S queries, which may take a moment as the endpoint is restarted after the
+	// change is published.
+	conn, err := net.Dial("udp4", newQueryEndpoint)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	buf := make([]byte, 1024)
+	require.Eventually(t, func() bool {
+		if _, err = conn.Write([]byte("\xFF\xFF\xFF\xFFTSource Engine Query\x00")); err != nil {
+			return false
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := conn.Read(buf)
+		return err == nil && n > 4 && buf[4] == 0x41
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The old endpoint is closed once its workers have stopped, so can be bound again.
+	require.Eventually(t, func() bool {
+		l, err := net.ListenPacket("udp4", queryEndpoint)
+		if err != nil {
+			return false
+		}
+
+		return l.Close() == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Stop())
+	require.Len(t, s.OnError(), 0)
+}
+
+func Test_ConfigChange_deallocationKeepsQueryEndpoint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	queryEndpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	svr, err := localproxytest.NewLocalProxy()
+	require.NoError(t, err)
+	defer svr.Close()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.json"), []byte(fmt.Sprintf(`{
+		"allocatedUUID": "alloc-uuid",
+		"queryPort": "%s",
+		"queryType": "a2s",
+		"serverID": "1234",
+		"serverLogDir": "%s",
+		"localProxyUrl": "%s"
+	}`, strings.Split(queryEndpoint, ":")[1], filepath.Join(dir, "logs"), svr.Host)), 0o600))
+
+	s, err := New(TypeAllocation, WithConfigPath(filepath.Join(dir, "server.json")))
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	<-s.OnConfigChange()
+	e := s.queryEndpoint
+
+	// Multiplay writes a minimal configuration on deallocation, which is applied without the fields required by
+	// Start.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "server.json"), []byte(`{"allocatedUUID": ""}`), 0o600))
+	change := <-s.OnConfigChange()
+	require.True(t, change.Changed("allocatedUUID"))
+	require.Empty(t, s.Config().AllocatedUUID)
+
+	// The query endpoint and protocol are unchanged.
+	s.queryMtx.Lock()
+	require.Same(t, e, s.queryEndpoint)
+	require.Equal(t, QueryProtocolA2S, s.queryResponder().name)
+	s.queryMtx.Unlock()
+
+	// Metrics remain unsupported by the running protocol, even though the query type defaults to SQP.
+	require.Equal(t, QueryProtocolSQP, s.Config().QueryType)
+	require.ErrorIs(t, s.SetMetric(0, 1.234), ErrMetricsUnsupported)
+
+	require.NoError(t, s.Stop())
+	require.Len(t, s.OnError(), 0)
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrConfigFieldInvalid represents that the value of a configuration field cannot be parsed as the type expected.
+	ErrConfigFieldInvalid = errors.New("field value is invalid")
+
+	// ErrInvalidExtraBinding represents that the value supplied to BindExtra is not a pointer to a struct, or one of
+	// its fields has a malformed `ugs` tag.
+	ErrInvalidExtraBinding = errors.New("invalid extra binding")
+
+	// durationType is the type of time.Duration, which is parsed differently to other integers.
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// ExtraString returns the extra configuration value with the provided key, or def if it is missing or empty.
+func (c Config) ExtraString(key string, def string) string {
+	if v := c.Extra[key]; v != "" {
+		return v
+	}
+
+	return def
+}
+
+// ExtraInt returns the extra configuration value with the provided key as an integer, or def if it is missing or
+// empty. If the value is not an integer, def is returned along with a *ConfigFieldError wrapping
+// ErrConfigFieldInvalid.
+func (c Config) ExtraInt(key string, def int) (int, error) {
+	return extraValue(c, key, def, strconv.Atoi)
+}
+
+// ExtraBool returns the extra configuration value with the provided key as a boolean, as accepted by
+// strconv.ParseBool. Errors are as per ExtraInt.
+func (c Config) ExtraBool(key string, def bool) (bool, error) {
+	return extraValue(c, key, def, strconv.ParseBool)
+}
+
+// ExtraDuration returns the extra configuration value with the provided key as a duration, as accepted by
+// time.ParseDuration. Errors are as per ExtraInt.
+func (c Config) ExtraDuration(key string, def time.Duration) (time.Duration, error) {
+	return extraValue(c, key, def, time.ParseDuration)
+}
+
+// ExtraJSON decodes the extra configuration value with the provided key as JSON into v. If the value is missing or
+// empty, v is left unmodified, so should hold the default. Errors are as per ExtraInt.
+func (c Config) ExtraJSON(key string, v any) error {
+	s := c.Extra[key]
+	if s == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(s), v); err != nil {
+		return &ConfigFieldError{Field: key, Err: fmt.Errorf("%w: %w", ErrConfigFieldInvalid, err)}
+	}
+
+	return nil
+}
+
+// BindExtra sets the fields of the struct pointed to by v from the extra configuration, allowing game settings to be
+// declared rather than parsed individually. Fields are bound by their `ugs` tag, which holds the key of the value
+// followed by optional comma-separated options:
+//
+//	MaxRounds int           `ugs:"maxRounds,default=5"`
+//	RoundTime time.Duration `ugs:"roundTime,optional"`
+//
+// The default option supplies the value used if the key is missing or empty, and must be the last option as the
+// default may contain commas. The optional option leaves the field unmodified if the key is missing or empty.
+// Otherwise, a missing key is reported as a *ConfigFieldError wrapping ErrConfigFieldRequired. Fields without a tag,
+// or with the tag "-", are ignored.
+//
+// Strings, booleans, integers, floats, durations and types implementing encoding.TextUnmarshaler are parsed from the
+// value directly, and any other type is decoded from the value as JSON. Values which cannot be parsed are reported as
+// a *ConfigFieldError wrapping ErrConfigFieldInvalid. The returned error joins the errors for every field.
+func (c Config) BindExtra(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a pointer to a struct", ErrInvalidExtraBinding, v)
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs []error
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, ok := f.Tag.Lookup("ugs")
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+
+		key, def, hasDefault, optional, err := parseExtraTag(tag)
+		if err != nil {
+			return fmt.Errorf("%w: field %s: %w", ErrInvalidExtraBinding, f.Name, err)
+		}
+
+		if key == "" {
+			key = f.Name
+		}
+
+		value := c.Extra[key]
+		switch {
+		case value != "":
+		case hasDefault:
+			value = def
+		case optional:
+			continue
+		default:
+			errs = append(errs, &ConfigFieldError{Field: key, Err: ErrConfigFieldRequired})
+			continue
+		}
+
+		if err = setExtraField(rv.Field(i), value); err != nil {
+			errs = append(errs, &ConfigFieldError{Field: key, Err: fmt.Errorf("%w: %w", ErrConfigFieldInvalid, err)})
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// extraValue returns the extra configuration value with the provided key converted by parse, or def if it is
+// missing, empty or cannot be parsed.
+func extraValue[T any](c Config, key string, def T, parse func(string) (T, error)) (T, error) {
+	s := c.Extra[key]
+	if s == "" {
+		return def, nil
+	}
+
+	v, err := parse(s)
+	if err != nil {
+		return def, &ConfigFieldError{Field: key, Err: fmt.Errorf("%w: %w", ErrConfigFieldInvalid, err)}
+	}
+
+	return v, nil
+}
+
+// parseExtraTag parses a `ugs` struct tag into its key and options.
+func parseExtraTag(tag string) (key string, def string, hasDefault bool, optional bool, err error) {
+	key, opts, _ := strings.Cut(tag, ",")
+	for opts != "" {
+		if def, hasDefault = strings.CutPrefix(opts, "default="); hasDefault {
+			break
+		}
+
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		if opt != "optional" {
+			return "", "", false, false, fmt.Errorf("unknown tag option %q", opt)
+		}
+
+		optional = true
+	}
+
+	return key, def, hasDefault, optional, nil
+}
+
+// setExtraField parses s into the struct field v.
+func setExtraField(v reflect.Value, s string) error {
+	if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+
+	default:
+		return json.Unmarshal([]byte(s), v.Addr().Interface())
+	}
+
+	return nil
+}
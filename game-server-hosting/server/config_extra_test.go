@@ -0,0 +1,142 @@
+package server
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Config_ExtraAccessors(t *testing.T) {
+	t.Parallel()
+
+	c := Config{
+		Extra: map[string]string{
+			"rounds":   "3",
+			"backfill": "true",
+			"timeout":  "90s",
+			"maps":     `["a","b"]`,
+			"invalid":  "abc",
+			"empty":    "",
+		},
+	}
+
+	require.Equal(t, "3", c.ExtraString("rounds", "x"))
+	require.Equal(t, "x", c.ExtraString("empty", "x"))
+
+	n, err := c.ExtraInt("rounds", 5)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	n, err = c.ExtraInt("missing", 5)
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+
+	n, err = c.ExtraInt("invalid", 5)
+	require.ErrorIs(t, err, ErrConfigFieldInvalid)
+	require.Equal(t, 5, n)
+
+	var fieldErr *ConfigFieldError
+	require.ErrorAs(t, err, &fieldErr)
+	require.Equal(t, "invalid", fieldErr.Field)
+
+	b, err := c.ExtraBool("backfill", false)
+	require.NoError(t, err)
+	require.True(t, b)
+
+	b, err = c.ExtraBool("empty", true)
+	require.NoError(t, err)
+	require.True(t, b)
+
+	d, err := c.ExtraDuration("timeout", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 90*time.Second, d)
+
+	_, err = c.ExtraDuration("invalid", time.Second)
+	require.ErrorIs(t, err, ErrConfigFieldInvalid)
+
+	maps := []string{"default"}
+	require.NoError(t, c.ExtraJSON("maps", &maps))
+	require.Equal(t, []string{"a", "b"}, maps)
+
+	maps = []string{"default"}
+	require.NoError(t, c.ExtraJSON("missing", &maps))
+	require.Equal(t, []string{"default"}, maps)
+
+	require.ErrorIs(t, c.ExtraJSON("invalid", &maps), ErrConfigFieldInvalid)
+}
+
+func Test_Config_BindExtra(t *testing.T) {
+	t.Parallel()
+
+	type settings struct {
+		MaxRounds  int            `ugs:"maxRounds,default=5"`
+		Mode       string         `ugs:"mode"`
+		Ranked     bool           `ugs:"ranked,optional"`
+		RoundTime  time.Duration  `ugs:"roundTime,default=2m"`
+		Gravity    float32        `ugs:"gravity,default=9.81"`
+		Slots      uint8          `ugs:"slots"`
+		Relay      netip.Addr     `ugs:"relay"`
+		Maps       []string       `ugs:"maps,default=[\"a\",\"b\"]"`
+		Weights    map[string]int `ugs:"weights,optional"`
+		Region     string         `ugs:",optional"`
+		Ignored    string         `ugs:"-"`
+		Untagged   string
+		unexported string `ugs:"unexported"` //nolint: unused
+	}
+
+	c := Config{
+		Extra: map[string]string{
+			"mode":     "ctf",
+			"slots":    "16",
+			"relay":    "10.0.0.1",
+			"Region":   "eu",
+			"Ignored":  "x",
+			"Untagged": "x",
+		},
+	}
+
+	var s settings
+	require.NoError(t, c.BindExtra(&s))
+	require.Equal(t, settings{
+		MaxRounds: 5,
+		Mode:      "ctf",
+		RoundTime: 2 * time.Minute,
+		Gravity:   9.81,
+		Slots:     16,
+		Relay:     netip.MustParseAddr("10.0.0.1"),
+		Maps:      []string{"a", "b"},
+		Region:    "eu",
+	}, s)
+
+	// Missing and invalid values are all reported.
+	c.Extra = map[string]string{
+		"maxRounds": "many",
+		"slots":     "256",
+		"relay":     "10.0.0.1",
+	}
+
+	err := c.BindExtra(&s)
+	require.ErrorIs(t, err, ErrConfigFieldRequired)
+	require.ErrorIs(t, err, ErrConfigFieldInvalid)
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 3)
+	require.Contains(t, err.Error(), "maxRounds: ")
+	require.Contains(t, err.Error(), "mode: field is required")
+	require.Contains(t, err.Error(), "slots: ")
+}
+
+func Test_Config_BindExtra_invalidBinding(t *testing.T) {
+	t.Parallel()
+
+	c := Config{}
+
+	var n int
+	require.ErrorIs(t, c.BindExtra(&n), ErrInvalidExtraBinding)
+	require.ErrorIs(t, c.BindExtra(struct{}{}), ErrInvalidExtraBinding)
+
+	var s struct {
+		A int `ugs:"a,required"`
+	}
+	require.ErrorIs(t, c.BindExtra(&s), ErrInvalidExtraBinding)
+}
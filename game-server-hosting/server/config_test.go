@@ -97,3 +97,73 @@ func Test_NewConfigFromFile_supported_values(t *testing.T) {
 		Extra:         map[string]string{},
 	}, cfg)
 }
+
+func Test_Config_Validate(t *testing.T) {
+	t.Parallel()
+
+	valid := Config{
+		LocalProxyURL: "http://localhost:8086",
+		MachineID:     "1234",
+		Port:          "9000",
+		QueryPort:     "9010",
+		QueryType:     QueryProtocolSQP,
+		ServerID:      "1234",
+		ServerLogDir:  "/logs",
+	}
+	require.NoError(t, valid.Validate())
+
+	optional := valid
+	optional.MachineID = ""
+	optional.Port = ""
+	require.NoError(t, optional.Validate())
+
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		field    string
+		expected error
+	}{
+		{name: "missing query port", modify: func(c *Config) { c.QueryPort = "" }, field: "queryPort", expected: ErrConfigFieldRequired},
+		{name: "query port out of range", modify: func(c *Config) { c.QueryPort = "65536" }, field: "queryPort", expected: ErrConfigPortOutOfRange},
+		{name: "port out of range", modify: func(c *Config) { c.Port = "0" }, field: "port", expected: ErrConfigPortOutOfRange},
+		{name: "non-numeric port", modify: func(c *Config) { c.Port = "abc" }, field: "port", expected: ErrConfigFieldNotNumeric},
+		{name: "missing server id", modify: func(c *Config) { c.ServerID = "" }, field: "serverID", expected: ErrConfigFieldRequired},
+		{name: "non-numeric server id", modify: func(c *Config) { c.ServerID = "abc" }, field: "serverID", expected: ErrConfigFieldNotNumeric},
+		{name: "non-numeric machine id", modify: func(c *Config) { c.MachineID = "1.5" }, field: "machineID", expected: ErrConfigFieldNotNumeric},
+		{name: "missing server log dir", modify: func(c *Config) { c.ServerLogDir = "" }, field: "serverLogDir", expected: ErrConfigFieldRequired},
+		{name: "relative local proxy url", modify: func(c *Config) { c.LocalProxyURL = "localhost:8086" }, field: "localProxyUrl", expected: ErrConfigInvalidURL},
+		{name: "unsupported query type", modify: func(c *Config) { c.QueryType = "foo" }, field: "queryType", expected: ErrUnsupportedQueryType},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := valid
+			test.modify(&c)
+
+			err := c.Validate()
+			require.ErrorIs(t, err, test.expected)
+
+			var fieldErr *ConfigFieldError
+			require.ErrorAs(t, err, &fieldErr)
+			require.Equal(t, test.field, fieldErr.Field)
+		})
+	}
+
+	// Fields only required by Start may be omitted from configuration which replaces it, but are otherwise checked.
+	require.NoError(t, (&Config{LocalProxyURL: valid.LocalProxyURL, QueryType: QueryProtocolSQP}).validateChange())
+	require.ErrorIs(t, (&Config{
+		LocalProxyURL: valid.LocalProxyURL,
+		QueryPort:     "abc",
+		QueryType:     QueryProtocolSQP,
+	}).validateChange(), ErrConfigFieldNotNumeric)
+
+	// Every problem is reported.
+	err := (&Config{}).Validate()
+	require.ErrorIs(t, err, ErrConfigFieldRequired)
+	require.ErrorIs(t, err, ErrConfigInvalidURL)
+	require.ErrorIs(t, err, ErrUnsupportedQueryType)
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 5)
+}
@@ -1,55 +1,73 @@
 package server
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"io"
+	"io/fs"
+	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-// watchForConfigChanges watches the provided configuration file for changes.
+// watchForConfigChanges watches the configuration file for changes, applying and publishing any new configuration.
+// Filesystem notifications are used where available, otherwise the file is polled.
 func (s *Server) watchForConfigChanges() {
-	w, _ := fsnotify.NewWatcher()
-	_ = w.Add(filepath.Dir(s.cfgFile))
-
 	s.wg.Add(1)
-	s.internalEventProcessorReady <- struct{}{}
 	defer s.wg.Done()
 
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+		poll   <-chan time.Time
+	)
+
+	interval := s.cfgPollInterval
+	if !s.cfgPolling {
+		w, err := newConfigFileWatcher(s.cfgFile)
+		if err == nil {
+			defer w.Close()
+			events, errs = w.Events, w.Errors
+		} else {
+			s.PushError(fmt.Errorf("error watching config file, polling instead: %w", err))
+			interval = DefaultConfigPollInterval
+		}
+	}
+
+	if events == nil {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		poll = t.C
+	}
+
+	s.internalEventProcessorReady <- struct{}{}
+
+	// Changes are only read once the file has been left unchanged for the debounce duration, so that partially
+	// written files are not read and a burst of writes results in a single change.
+	var debounce <-chan time.Time
+
 	for {
 		select {
-		case evt, ok := <-w.Events:
+		case evt, ok := <-events:
 			if !ok {
 				return
 			}
 
-			// Ignore events for other files.
-			if evt.Name != s.cfgFile {
-				continue
+			if s.isConfigFileEvent(evt) {
+				debounce = time.After(s.cfgDebounceDuration)
 			}
 
-			// We only care about when the config file has been rewritten.
-			if evt.Op&fsnotify.Write != fsnotify.Write {
-				continue
-			}
-
-			c, err := newConfigFromFile(s.cfgFile)
-			if err != nil {
-				// Multiplay truncates the file when a deallocation occurs,
-				// which results in two writes. The first write will produce an
-				// empty file, meaning JSON parsing will fail.
-				if !errors.Is(err, io.EOF) {
-					s.PushError(fmt.Errorf("error parsing new configuration: %w", err))
-				}
+		case <-debounce:
+			debounce = nil
+			s.reloadConfig()
 
-				continue
-			}
+		case <-poll:
+			s.reloadConfig()
 
-			s.setConfig(c)
-
-		case err, ok := <-w.Errors:
+		case err, ok := <-errs:
 			if !ok {
 				return
 			}
@@ -57,10 +75,82 @@ func (s *Server) watchForConfigChanges() {
 			s.PushError(fmt.Errorf("error watching config file: %w", err))
 
 		case <-s.done:
-			_ = w.Close()
 			close(s.internalEventProcessorReady)
 
 			return
 		}
 	}
 }
+
+// newConfigFileWatcher creates a watcher for the directory containing the configuration file. The directory is
+// watched, rather than the file, so that the file being replaced is detected.
+func newConfigFileWatcher(configFile string) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = w.Add(filepath.Dir(configFile)); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// isConfigFileEvent determines whether a filesystem event may have changed the configuration file. This is the case
+// for writes to the file, and for any file created in its directory: writers which rename a temporary file over the
+// configuration file produce a create event for it, and mounted configuration may be replaced by swapping a symlink
+// to its directory. Events which do not change its contents are ignored when the file is read.
+func (s *Server) isConfigFileEvent(evt fsnotify.Event) bool {
+	if evt.Has(fsnotify.Create) {
+		return true
+	}
+
+	return evt.Has(fsnotify.Write) && filepath.Clean(evt.Name) == filepath.Clean(s.cfgFile)
+}
+
+// reloadConfig reads the configuration file, applying and publishing the configuration if its contents have changed
+// since it was last read.
+func (s *Server) reloadConfig() {
+	defer s.cfgReloads.Add(1)
+
+	data, err := os.ReadFile(s.cfgFile)
+	if err != nil {
+		// The file may be briefly missing while it is replaced.
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.PushError(fmt.Errorf("error reading new configuration: %w", err))
+		}
+
+		return
+	}
+
+	// Multiplay truncates the file when a deallocation occurs, before writing the new configuration, so an empty
+	// file is treated as incomplete rather than invalid.
+	if len(bytes.TrimSpace(data)) == 0 {
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	if hash == s.cfgHash {
+		return
+	}
+
+	// The hash is recorded even if the configuration is invalid, so that the error is only reported once.
+	s.cfgHash = hash
+
+	c, err := newConfigFromJSON(data)
+	if err != nil {
+		s.PushError(fmt.Errorf("error parsing new configuration: %w", err))
+		return
+	}
+
+	if err = c.validateChange(); err != nil {
+		s.PushError(fmt.Errorf("invalid new configuration: %w", err))
+		return
+	}
+
+	if err = s.applyQueryConfig(s.setConfig(c)); err != nil {
+		s.PushError(fmt.Errorf("error applying new query configuration: %w", err))
+	}
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -38,5 +41,102 @@ func Test_watchConfig(t *testing.T) {
 	ev = <-g.OnConfigurationChanged()
 	require.Empty(t, ev.AllocatedUUID)
 
+	// Invalid configuration is reported, rather than applied.
+	require.NoError(t, os.WriteFile(p, []byte(`{
+		"allocatedUUID": "alloc-uuid",
+		"queryPort": "70000"
+	}`), 0o600))
+	err = <-g.OnError()
+	require.ErrorIs(t, err, ErrConfigPortOutOfRange)
+	require.Empty(t, g.Config().AllocatedUUID)
+
 	close(g.done)
 }
+
+// validConfig returns a valid configuration document with the provided allocation ID.
+func validConfig(allocationID string) []byte {
+	return []byte(fmt.Sprintf(`{
+		"allocatedUUID": "%s",
+		"queryPort": "9010",
+		"serverID": "1234",
+		"serverLogDir": "/logs"
+	}`, allocationID))
+}
+
+// startConfigWatcher starts watching a new configuration file for changes, returning the server and file path.
+func startConfigWatcher(t *testing.T, opts ...Option) (*Server, string) {
+	t.Helper()
+
+	p := path.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(p, validConfig(""), 0o600))
+
+	s, err := New(TypeAllocation, append([]Option{WithConfigPath(p), WithConfigDebounceDuration(20 * time.Millisecond)}, opts...)...)
+	require.NoError(t, err)
+
+	// As per Start, the initial contents are not a change.
+	s.cfgHash = sha256.Sum256(validConfig(""))
+
+	go s.watchForConfigChanges()
+	<-s.internalEventProcessorReady
+	t.Cleanup(func() {
+		close(s.done)
+	})
+
+	return s, p
+}
+
+func Test_watchConfig_atomicRename(t *testing.T) {
+	t.Parallel()
+
+	s, p := startConfigWatcher(t)
+
+	tmp := p + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, validConfig("alloc-uuid"), 0o600))
+	require.NoError(t, os.Rename(tmp, p))
+
+	ev := <-s.OnConfigurationChanged()
+	require.Equal(t, "alloc-uuid", ev.AllocatedUUID)
+}
+
+func Test_watchConfig_debounceAndDeduplicate(t *testing.T) {
+	t.Parallel()
+
+	s, p := startConfigWatcher(t)
+
+	// A burst of writes results in a single change with the final contents.
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, os.WriteFile(p, validConfig(id), 0o600))
+	}
+
+	ev := <-s.OnConfigChange()
+	require.Equal(t, "c", ev.New.AllocatedUUID)
+	require.Equal(t, "", ev.Old.AllocatedUUID)
+	require.Equal(t, "c", (<-s.OnConfigurationChanged()).AllocatedUUID)
+
+	// Rewriting identical contents is not applied once the file has been reloaded.
+	reloads := s.cfgReloads.Load()
+	require.NoError(t, os.WriteFile(p, validConfig("c"), 0o600))
+	require.Eventually(t, func() bool {
+		return s.cfgReloads.Load() > reloads
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, s.OnConfigurationChanged(), 0)
+	require.Len(t, s.OnConfigChange(), 0)
+	require.Len(t, s.OnError(), 0)
+}
+
+func Test_watchConfig_polling(t *testing.T) {
+	t.Parallel()
+
+	s, p := startConfigWatcher(t, WithConfigPolling(10*time.Millisecond))
+
+	require.NoError(t, os.WriteFile(p, validConfig("alloc-uuid"), 0o600))
+	ev := <-s.OnConfigurationChanged()
+	require.Equal(t, "alloc-uuid", ev.AllocatedUUID)
+
+	// Empty files are ignored, as they are written while the file is being replaced.
+	require.NoError(t, os.WriteFile(p, nil, 0o600))
+	require.NoError(t, os.WriteFile(p, validConfig(""), 0o600))
+	ev = <-s.OnConfigurationChanged()
+	require.Empty(t, ev.AllocatedUUID)
+	require.Len(t, s.OnError(), 0)
+}
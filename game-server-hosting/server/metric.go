@@ -0,0 +1,95 @@
+package server
+
+import (
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
+)
+
+type (
+	// Metric represents a named metric, which is reported in the metric index allocated to it by RegisterMetric.
+	Metric struct {
+		s     *Server
+		name  string
+		index byte
+	}
+)
+
+// RegisterMetric allocates the next free metric index to a metric with the provided name, returning a handle which
+// sets its value. Indexes are allocated in order of registration, starting at 0, so values set with SetMetric
+// directly may be overwritten by named metrics. ErrMetricAlreadyRegistered is returned if the name is already
+// registered and ErrMetricsExhausted if all sqp.MaxMetrics indexes are allocated.
+func (s *Server) RegisterMetric(name string) (Metric, error) {
+	if name == "" {
+		return Metric{}, ErrMetricNameEmpty
+	}
+
+	s.metricIndexesMtx.Lock()
+	defer s.metricIndexesMtx.Unlock()
+
+	if _, ok := s.metricIndexes[name]; ok {
+		return Metric{}, ErrMetricAlreadyRegistered
+	}
+
+	if len(s.metricIndexes) >= sqp.MaxMetrics {
+		return Metric{}, ErrMetricsExhausted
+	}
+
+	if s.metricIndexes == nil {
+		s.metricIndexes = make(map[string]byte, sqp.MaxMetrics)
+	}
+
+	index := byte(len(s.metricIndexes))
+	s.metricIndexes[name] = index
+
+	return Metric{s: s, name: name, index: index}, nil
+}
+
+// MetricIndexes returns a copy of the mapping of registered metric names to the metric indexes they are reported in,
+// so that consumers of the metrics can label them.
+func (s *Server) MetricIndexes() map[string]byte {
+	s.metricIndexesMtx.Lock()
+	defer s.metricIndexesMtx.Unlock()
+
+	indexes := make(map[string]byte, len(s.metricIndexes))
+	for k, v := range s.metricIndexes {
+		indexes[k] = v
+	}
+
+	return indexes
+}
+
+// Name returns the name of the metric.
+func (m Metric) Name() string {
+	return m.name
+}
+
+// Index returns the metric index the metric is reported in.
+func (m Metric) Index() byte {
+	return m.index
+}
+
+// Value returns the current value of the metric.
+func (m Metric) Value() float32 {
+	metrics := m.s.state.Load().Metrics
+	if int(m.index) >= len(metrics) {
+		return 0
+	}
+
+	return metrics[m.index]
+}
+
+// Set sets the value of the metric. Errors are as per Server.SetMetric.
+func (m Metric) Set(value float32) error {
+	return m.s.SetMetric(m.index, value)
+}
+
+// Add adds delta to the value of the metric, returning the new value. Errors are as per Server.SetMetric.
+func (m Metric) Add(delta float32) (float32, error) {
+	return m.s.updateMetric(m.index, func(value float32) float32 {
+		return value + delta
+	})
+}
+
+// Inc increments the value of the metric by one, returning the new value. Errors are as per Server.SetMetric.
+func (m Metric) Inc() (float32, error) {
+	return m.Add(1)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+// useQueryProtocol sets the query protocol the server responds with, without starting the query endpoint.
+func useQueryProtocol(s *Server, name QueryProtocol) {
+	s.queryProto.Store(&queryProtocolResponder{name: name})
+}
+
+func Test_RegisterMetric(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+	useQueryProtocol(s, QueryProtocolSQP)
+
+	players, err := s.RegisterMetric("players")
+	require.NoError(t, err)
+	require.Equal(t, "players", players.Name())
+	require.Equal(t, byte(0), players.Index())
+
+	fps, err := s.RegisterMetric("fps")
+	require.NoError(t, err)
+	require.Equal(t, byte(1), fps.Index())
+
+	_, err = s.RegisterMetric("fps")
+	require.ErrorIs(t, err, ErrMetricAlreadyRegistered)
+
+	_, err = s.RegisterMetric("")
+	require.ErrorIs(t, err, ErrMetricNameEmpty)
+
+	require.Equal(t, map[string]byte{"players": 0, "fps": 1}, s.MetricIndexes())
+
+	require.NoError(t, fps.Set(59.5))
+	v, err := players.Inc()
+	require.NoError(t, err)
+	require.Equal(t, float32(1), v)
+	v, err = players.Add(2.5)
+	require.NoError(t, err)
+	require.Equal(t, float32(3.5), v)
+	require.Equal(t, float32(3.5), players.Value())
+	require.Equal(t, []float32{3.5, 59.5}, s.state.Load().Metrics)
+
+	for i := len(s.MetricIndexes()); i < sqp.MaxMetrics; i++ {
+		_, err = s.RegisterMetric(fmt.Sprintf("metric%d", i))
+		require.NoError(t, err)
+	}
+
+	_, err = s.RegisterMetric("extra")
+	require.ErrorIs(t, err, ErrMetricsExhausted)
+
+	useQueryProtocol(s, QueryProtocolA2S)
+	require.ErrorIs(t, fps.Set(1), ErrMetricsUnsupported)
+	_, err = fps.Inc()
+	require.ErrorIs(t, err, ErrMetricsUnsupported)
+}
+
+func Test_Metric_concurrentAdd(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+	useQueryProtocol(s, QueryProtocolSQP)
+
+	m, err := s.RegisterMetric("kills")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = m.Inc()
+		}()
+	}
+
+	wg.Wait()
+	require.Equal(t, float32(100), m.Value())
+}
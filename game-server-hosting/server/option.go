@@ -7,7 +7,8 @@ type (
 	Option func(s *Server)
 )
 
-// WithQueryWriteBuffer sets the write buffer size for the query handler.
+// WithQueryWriteBuffer sets the write buffer size for the query handler. For query protocols which support it, this
+// is also the maximum size of a single response packet, above which responses are split across multiple packets.
 func WithQueryWriteBuffer(sizeBytes int) Option {
 	return func(s *Server) {
 		s.queryWriteBufferSizeBytes = sizeBytes
@@ -35,6 +36,43 @@ func WithQueryReadDeadlineDuration(duration time.Duration) Option {
 	}
 }
 
+// WithQueryBindMode sets the IP version(s) the query endpoint is bound on. The default is QueryBindIPv4.
+func WithQueryBindMode(mode QueryBindMode) Option {
+	return func(s *Server) {
+		s.queryBindMode = mode
+	}
+}
+
+// WithQueryBindConfiguredAddress binds the query endpoint to the address provided in the server configuration rather
+// than all interfaces: the `ip` field for QueryBindIPv4, or the `ipv6` field for QueryBindIPv6. It cannot be used with
+// QueryBindDualStack.
+func WithQueryBindConfiguredAddress() Option {
+	return func(s *Server) {
+		s.queryBindConfiguredAddress = true
+	}
+}
+
+// WithQueryWorkers sets the number of goroutines reading and responding to query requests, so that a burst of
+// requests does not queue behind slow responses. Values below 1 are treated as 1.
+func WithQueryWorkers(workers int) Option {
+	return func(s *Server) {
+		if workers < 1 {
+			workers = 1
+		}
+
+		s.queryWorkers = workers
+	}
+}
+
+// WithQueryReusePort gives each query worker its own socket bound with SO_REUSEPORT, so that the kernel distributes
+// incoming requests between them. This is only supported on Linux; on other platforms starting the query endpoint
+// fails with ErrReusePortUnsupported.
+func WithQueryReusePort() Option {
+	return func(s *Server) {
+		s.queryReusePort = true
+	}
+}
+
 // WithConfigPath sets the configuration file to use when starting the server. In most circumstances, the default
 // value is reasonable to use.
 func WithConfigPath(path string) Option {
@@ -42,3 +80,100 @@ func WithConfigPath(path string) Option {
 		s.cfgFile = path
 	}
 }
+
+// WithConfigDebounceDuration sets the time the configuration file must be left unchanged after a change is detected
+// before it is read, so that a burst of writes results in a single configuration change. The default is
+// DefaultConfigDebounceDuration.
+func WithConfigDebounceDuration(duration time.Duration) Option {
+	return func(s *Server) {
+		s.cfgDebounceDuration = duration
+	}
+}
+
+// WithConfigPolling reads the configuration file at the provided interval to detect changes, rather than relying on
+// filesystem notifications, which are unavailable on some filesystems. Polling is also used, at
+// DefaultConfigPollInterval, if filesystem notifications cannot be set up.
+func WithConfigPolling(interval time.Duration) Option {
+	return func(s *Server) {
+		s.cfgPolling = true
+		s.cfgPollInterval = interval
+	}
+}
+
+// WithStatelessQueryChallenges enables stateless challenges for the query handler, where challenges are derived from
+// the client address and a rotating secret rather than stored per client. This bounds memory usage when the query port
+// receives a flood of requests from spoofed addresses. Query protocols which do not implement
+// proto.StatelessChallengeResponder are unaffected.
+func WithStatelessQueryChallenges() Option {
+	return func(s *Server) {
+		s.queryStatelessChallenges = true
+	}
+}
+
+// WithQueryRateLimitPerIP limits the rate of query requests answered for each source IP address, using a token bucket
+// which refills at requestsPerSecond up to burst requests.
+func WithQueryRateLimitPerIP(requestsPerSecond float64, burst int) Option {
+	return func(s *Server) {
+		s.queryLimits.perIP = newRateLimit(requestsPerSecond, burst)
+	}
+}
+
+// WithQueryRateLimitPerSubnet limits the rate of query requests answered for each source subnet, using a token bucket
+// which refills at requestsPerSecond up to burst requests. Subnets are determined by WithQuerySubnetPrefix.
+func WithQueryRateLimitPerSubnet(requestsPerSecond float64, burst int) Option {
+	return func(s *Server) {
+		s.queryLimits.perSubnet = newRateLimit(requestsPerSecond, burst)
+	}
+}
+
+// WithQuerySubnetPrefix sets the prefix lengths used to group source addresses into subnets for
+// WithQueryRateLimitPerSubnet. The defaults are DefaultQuerySubnetPrefixIPv4 and DefaultQuerySubnetPrefixIPv6.
+func WithQuerySubnetPrefix(ipv4Bits int, ipv6Bits int) Option {
+	return func(s *Server) {
+		s.queryLimits.subnetPrefixIPv4 = ipv4Bits
+		s.queryLimits.subnetPrefixIPv6 = ipv6Bits
+	}
+}
+
+// WithQueryGlobalRateLimit limits the rate of query response packets sent across all sources, using a token bucket
+// which refills at responsesPerSecond up to burst packets.
+func WithQueryGlobalRateLimit(responsesPerSecond float64, burst int) Option {
+	return func(s *Server) {
+		s.queryLimits.global = newRateLimit(responsesPerSecond, burst)
+	}
+}
+
+// WithQueryMaxAmplification limits the size of query responses to ratio times the size of the request, until the
+// client has satisfied a challenge. This prevents the query port being used to amplify traffic towards spoofed
+// source addresses. Only query protocols which implement proto.ChallengeVerifier can send larger responses, unless
+// exempted with WithQueryAmplificationExemption.
+func WithQueryMaxAmplification(ratio float64) Option {
+	return func(s *Server) {
+		s.queryLimits.maxAmplificationRatio = ratio
+	}
+}
+
+// WithQueryAmplificationExemption exempts responses of the provided query protocols from WithQueryMaxAmplification.
+// This is intended for protocols without a challenge handshake, such as QueryProtocolQ3, whose responses would
+// otherwise be dropped. Exempt protocols can be used to amplify traffic towards spoofed source addresses, so should
+// only be used alongside rate limits.
+func WithQueryAmplificationExemption(protocols ...QueryProtocol) Option {
+	return func(s *Server) {
+		if s.queryLimits.amplificationExempt == nil {
+			s.queryLimits.amplificationExempt = map[QueryProtocol]bool{}
+		}
+
+		for _, p := range protocols {
+			s.queryLimits.amplificationExempt[p] = true
+		}
+	}
+}
+
+// newRateLimit creates a rate limit, ensuring the burst allows at least one request.
+func newRateLimit(rate float64, burst int) rateLimit {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return rateLimit{rate: rate, burst: burst}
+}
@@ -41,3 +41,62 @@ func Test_WithConfigPath(t *testing.T) {
 	WithConfigPath("foo")(s)
 	require.Equal(t, "foo", s.cfgFile)
 }
+
+func Test_WithStatelessQueryChallenges(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	WithStatelessQueryChallenges()(s)
+	require.True(t, s.queryStatelessChallenges)
+}
+
+func Test_WithQueryLimits(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	WithQueryRateLimitPerIP(10, 20)(s)
+	WithQueryRateLimitPerSubnet(100, 0)(s)
+	WithQuerySubnetPrefix(16, 48)(s)
+	WithQueryGlobalRateLimit(1000, 2000)(s)
+	WithQueryMaxAmplification(3)(s)
+	WithQueryAmplificationExemption(QueryProtocolQ3)(s)
+	WithQueryAmplificationExemption(QueryProtocolGameSpy4)(s)
+	require.Equal(t, queryLimits{
+		perIP:                 rateLimit{rate: 10, burst: 20},
+		perSubnet:             rateLimit{rate: 100, burst: 1},
+		subnetPrefixIPv4:      16,
+		subnetPrefixIPv6:      48,
+		global:                rateLimit{rate: 1000, burst: 2000},
+		maxAmplificationRatio: 3,
+		amplificationExempt:   map[QueryProtocol]bool{QueryProtocolQ3: true, QueryProtocolGameSpy4: true},
+	}, s.queryLimits)
+}
+
+func Test_WithQueryBind(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	WithQueryBindMode(QueryBindDualStack)(s)
+	WithQueryBindConfiguredAddress()(s)
+	require.Equal(t, QueryBindDualStack, s.queryBindMode)
+	require.True(t, s.queryBindConfiguredAddress)
+}
+
+func Test_WithQueryWorkers(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	WithQueryWorkers(4)(s)
+	WithQueryReusePort()(s)
+	require.Equal(t, 4, s.queryWorkers)
+	require.True(t, s.queryReusePort)
+
+	WithQueryWorkers(0)(s)
+	require.Equal(t, 1, s.queryWorkers)
+}
+
+func Test_WithConfigWatching(t *testing.T) {
+	t.Parallel()
+	s := &Server{}
+	WithConfigDebounceDuration(time.Second)(s)
+	WithConfigPolling(2 * time.Second)(s)
+	require.Equal(t, time.Second, s.cfgDebounceDuration)
+	require.Equal(t, 2*time.Second, s.cfgPollInterval)
+	require.True(t, s.cfgPolling)
+}
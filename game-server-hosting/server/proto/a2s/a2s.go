@@ -3,7 +3,9 @@ package a2s
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
 	"runtime"
+	"strings"
 	"sync/atomic"
 
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
@@ -13,7 +15,13 @@ type (
 	// QueryResponder implements proto.QueryResponder for the A2S protocol.
 	QueryResponder struct {
 		*proto.QueryBase
-		enc *encoder
+
+		// splitID is the ID of the most recent split response.
+		splitID uint32
+
+		// cache holds encoded responses which do not vary between clients for the current snapshot of the query
+		// state. It is nil if responses are not cached.
+		cache *proto.ResponseCache
 	}
 
 	// challengeWireFormat describes the format of a S2C_CHALLENGE query response.
@@ -39,15 +47,42 @@ type (
 		Visibility  byte
 		VACEnabled  byte
 		Version     string
+
+		// Extra Data Flag (EDF) fields, which are only present if the corresponding flag is set in EDF.
+		EDF          *byte
+		GamePort     *uint16
+		SteamID      *uint64
+		SourceTVPort *uint16
+		SourceTVName *string
+		Keywords     *string
+		GameID       *uint64
 	}
 
 	// infoRequest represents the request format for an A2S_INFO query.
 	infoRequest struct {
-		Payload   string
+		Payload   []byte
 		Challenge uint32
 	}
 )
 
+const (
+	// Keys of the responses held in the response cache.
+	cacheKeyInfo = uint32(iota)
+	cacheKeyRules
+)
+
+const (
+	// noChallenge is the challenge value clients send to request a new challenge.
+	noChallenge = 0xFFFFFFFF
+
+	// Extra Data Flags, which denote the optional fields present in an A2S_INFO response.
+	edfGamePort = byte(0x80)
+	edfSteamID  = byte(0x10)
+	edfSourceTV = byte(0x40)
+	edfKeywords = byte(0x20)
+	edfGameID   = byte(0x01)
+)
+
 var (
 	a2sInfoRequest       = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x54}
 	a2sInfoResponse      = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x49}
@@ -55,90 +90,238 @@ var (
 )
 
 // NewQueryResponder returns creates a new responder capable of responding
-// to a2s-formatted queries.
+// to a2s-formatted queries, using the provided query state. The state is read for each query, so it may be modified
+// between queries.
 func NewQueryResponder(state *proto.QueryState) (*QueryResponder, error) {
 	q := &QueryResponder{
 		QueryBase: &proto.QueryBase{
 			State: state,
 		},
-		enc: &encoder{},
 	}
 
 	return q, nil
 }
 
-// Respond writes a query response to the requester in the SQP wire protocol.
+// NewQueryResponderFromSnapshot creates a new responder capable of responding to a2s-formatted queries,
+// using the query state snapshot currently held by the provided pointer. Snapshots must not be modified, as
+// responses are cached until the snapshot is replaced.
+func NewQueryResponderFromSnapshot(state *atomic.Pointer[proto.QueryState]) (*QueryResponder, error) {
+	q := &QueryResponder{
+		QueryBase: &proto.QueryBase{
+			Snapshots: state,
+		},
+		cache: &proto.ResponseCache{},
+	}
+
+	return q, nil
+}
+
+// Respond writes a query response to the requester in the A2S wire protocol.
 func (q *QueryResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
-	if bytes.Equal(buf[0:5], a2sInfoRequest) {
-		return q.handleInfoRequest(clientAddress, buf)
+	return q.AppendResponse(nil, clientAddress, buf)
+}
+
+// AppendResponse appends a query response in the A2S wire protocol to dst.
+func (q *QueryResponder) AppendResponse(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
+	if len(buf) < 5 {
+		return nil, errInvalidPacketLength
+	}
+
+	switch {
+	case bytes.Equal(buf[0:5], a2sInfoRequest):
+		return q.handleInfoRequest(dst, clientAddress, buf)
+
+	case bytes.Equal(buf[0:5], a2sPlayerRequest):
+		return q.handlePlayerRequest(dst, clientAddress, buf)
+
+	case bytes.Equal(buf[0:5], a2sRulesRequest):
+		return q.handleRulesRequest(dst, clientAddress, buf)
 	}
 
 	return nil, NewUnsupportedQueryError(buf[0:5])
 }
 
-func (q *QueryResponder) handleInfoRequest(clientAddress string, buf []byte) ([]byte, error) {
-	info, err := parseInfoRequest(buf)
+// challengeResponse generates a challenge for the client and appends it to dst in the S2C_CHALLENGE format.
+func (q *QueryResponder) challengeResponse(dst []byte, clientAddress string) ([]byte, error) {
+	challenge, err := q.GenerateChallenge(clientAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	var f any
+	return challengeWireFormat{
+		Header:    s2cChallengeResponse,
+		Challenge: challenge,
+	}.AppendTo(dst), nil
+}
+
+// handleInfoRequest handles an incoming A2S_INFO request.
+func (q *QueryResponder) handleInfoRequest(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
+	info, err := parseInfoRequest(buf)
+	if err != nil {
+		return nil, err
+	}
 
 	// If no challenge has been supplied, respond with one. Expect it on the next request.
 	if info.Challenge == 0 {
-		challenge, handleErr := q.GenerateChallenge(clientAddress)
-		if handleErr != nil {
-			return nil, handleErr
-		}
+		return q.challengeResponse(dst, clientAddress)
+	}
 
-		f = challengeWireFormat{
-			Header:    s2cChallengeResponse,
-			Challenge: challenge,
-		}
-	} else {
-		if err = q.ChallengeMatchesForClient(clientAddress, info.Challenge); err != nil {
-			return nil, err
-		}
+	if err = q.ChallengeMatchesForClient(clientAddress, info.Challenge); err != nil {
+		return nil, err
+	}
 
-		w := infoWireFormat{
-			Header:      a2sInfoResponse,
-			Protocol:    1,
-			ServerName:  "n/a",
-			GameMap:     "n/a",
-			GameFolder:  "n/a",
-			GameName:    "n/a",
-			ServerType:  'd', // d = dedicated server, which is the only supported option currently
-			Environment: environmentFromRuntime(runtime.GOOS),
-		}
+	return q.appendCached(dst, cacheKeyInfo, appendInfo), nil
+}
+
+// appendInfo appends an A2S_INFO response for the provided query state to dst.
+func appendInfo(dst []byte, qs *proto.QueryState) []byte {
+	w := infoWireFormat{
+		Header:      a2sInfoResponse,
+		Protocol:    1,
+		ServerName:  "n/a",
+		GameMap:     "n/a",
+		GameFolder:  "n/a",
+		GameName:    "n/a",
+		ServerType:  'd', // d = dedicated server, which is the only supported option currently
+		Environment: environmentFromRuntime(runtime.GOOS),
+	}
 
-		if q.State != nil {
-			w.ServerName = q.State.ServerName
-			w.GameMap = q.State.Map
-			w.PlayerCount = byte(atomic.LoadInt32(&q.State.CurrentPlayers))
-			w.MaxPlayers = byte(q.State.MaxPlayers)
-			w.GameName = q.State.GameType
+	if qs != nil {
+		w.ServerName = qs.ServerName
+		w.GameMap = qs.Map
+		w.PlayerCount = byte(qs.CurrentPlayers)
+		w.MaxPlayers = byte(qs.MaxPlayers)
+		w.GameName = qs.GameType
+		w.Version = qs.Version
+		w.NumBots = byte(qs.Bots)
+
+		if qs.PasswordProtected {
+			w.Visibility = 1
 		}
 
-		f = w
+		setExtraDataFields(&w, qs)
 	}
 
-	resp := bytes.NewBuffer(nil)
+	return w.AppendTo(dst)
+}
 
-	if err := proto.WireWrite(resp, q.enc, f); err != nil {
-		return nil, err
+// appendCached appends the response cached under key for the current query state to dst. If the response is not
+// cached, it is appended using encode and cached until the query state changes, unless responses are not cached.
+func (q *QueryResponder) appendCached(dst []byte, key uint32, encode func(dst []byte, qs *proto.QueryState) []byte) []byte {
+	qs := q.Snapshot()
+	if qs == nil || q.cache == nil {
+		return encode(dst, qs)
+	}
+
+	if resp, ok := q.cache.Get(qs, key); ok {
+		return append(dst, resp...)
+	}
+
+	start := len(dst)
+	dst = encode(dst, qs)
+	q.cache.Put(qs, key, dst[start:])
+	return dst
+}
+
+// setExtraDataFields sets any optional Extra Data Flag fields on the A2S_INFO response which have values in the
+// provided query state. The flag byte itself is omitted if no fields are set.
+func setExtraDataFields(w *infoWireFormat, qs *proto.QueryState) {
+	var edf byte
+
+	if qs.Port != 0 {
+		edf |= edfGamePort
+		port := qs.Port
+		w.GamePort = &port
+	}
+
+	if qs.SteamID != 0 {
+		edf |= edfSteamID
+		id := qs.SteamID
+		w.SteamID = &id
+	}
+
+	if qs.SourceTVPort != 0 {
+		edf |= edfSourceTV
+		port, name := qs.SourceTVPort, qs.SourceTVName
+		w.SourceTVPort = &port
+		w.SourceTVName = &name
+	}
+
+	if len(qs.Keywords) > 0 {
+		edf |= edfKeywords
+		keywords := strings.Join(qs.Keywords, ",")
+		w.Keywords = &keywords
+	}
+
+	if qs.GameID != 0 {
+		edf |= edfGameID
+		id := qs.GameID
+		w.GameID = &id
+	}
+
+	if edf != 0 {
+		w.EDF = &edf
+	}
+}
+
+// AppendTo appends the challenge response in the A2S wire format to dst.
+func (c challengeWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, c.Header...)
+	return binary.LittleEndian.AppendUint32(dst, c.Challenge)
+}
+
+// AppendTo appends the A2S_INFO response in the A2S wire format to dst. Extra Data Flag fields are only appended if
+// they are set.
+func (w *infoWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, w.Header...)
+	dst = append(dst, w.Protocol)
+	dst = appendString(dst, w.ServerName)
+	dst = appendString(dst, w.GameMap)
+	dst = appendString(dst, w.GameFolder)
+	dst = appendString(dst, w.GameName)
+	dst = binary.LittleEndian.AppendUint16(dst, uint16(w.SteamAppID))
+	dst = append(dst, w.PlayerCount, w.MaxPlayers, w.NumBots, w.ServerType, w.Environment, w.Visibility, w.VACEnabled)
+	dst = appendString(dst, w.Version)
+
+	if w.EDF != nil {
+		dst = append(dst, *w.EDF)
+	}
+
+	if w.GamePort != nil {
+		dst = binary.LittleEndian.AppendUint16(dst, *w.GamePort)
+	}
+
+	if w.SteamID != nil {
+		dst = binary.LittleEndian.AppendUint64(dst, *w.SteamID)
+	}
+
+	if w.SourceTVPort != nil {
+		dst = binary.LittleEndian.AppendUint16(dst, *w.SourceTVPort)
 	}
 
-	return resp.Bytes(), nil
+	if w.SourceTVName != nil {
+		dst = appendString(dst, *w.SourceTVName)
+	}
+
+	if w.Keywords != nil {
+		dst = appendString(dst, *w.Keywords)
+	}
+
+	if w.GameID != nil {
+		dst = binary.LittleEndian.AppendUint64(dst, *w.GameID)
+	}
+
+	return dst
 }
 
-// parseInfoRequest parses the incoming request as a A2S_INFO request.
-func parseInfoRequest(buf []byte) (*infoRequest, error) {
+// parseInfoRequest parses the incoming request as a A2S_INFO request. The payload of the returned request references
+// buf.
+func parseInfoRequest(buf []byte) (infoRequest, error) {
 	if !bytes.Equal(buf[0:5], a2sInfoRequest) {
-		return nil, errNotAnInfoRequest
+		return infoRequest{}, errNotAnInfoRequest
 	}
 
-	info := &infoRequest{}
-	rBuf := bytes.NewBuffer(buf[5:])
+	info := infoRequest{}
 
 	// Read through buffer until we reach a null-terminator
 	n := 5
@@ -150,18 +333,11 @@ func parseInfoRequest(buf []byte) (*infoRequest, error) {
 	}
 
 	// Read the payload if it exists
-	l := n - 5
-	if l > 0 {
-		d := make([]byte, l)
-		if err := binary.Read(rBuf, binary.LittleEndian, &d); err != nil {
-			return nil, err
-		}
-
-		info.Payload = string(d)
+	if n > 5 {
+		info.Payload = buf[5:n]
 	}
 
 	// Skip past null terminator
-	_ = rBuf.Next(1)
 	n++
 
 	// No space for a challenge - return as-is.
@@ -169,10 +345,12 @@ func parseInfoRequest(buf []byte) (*infoRequest, error) {
 		return info, nil
 	}
 
-	if err := binary.Read(rBuf, binary.LittleEndian, &info.Challenge); err != nil {
-		return nil, err
+	if len(buf)-n < 4 {
+		return infoRequest{}, io.ErrUnexpectedEOF
 	}
 
+	info.Challenge = binary.LittleEndian.Uint32(buf[n:])
+
 	return info, nil
 }
 
@@ -5,7 +5,9 @@ import (
 	"encoding/binary"
 	"io"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
 	"github.com/stretchr/testify/require"
@@ -72,6 +74,299 @@ func Test_Respond(t *testing.T) {
 	)
 }
 
+func Test_Respond_ExtraDataFlags(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers:    1,
+		MaxPlayers:        2,
+		ServerName:        "foo",
+		Map:               "map",
+		GameType:          "type",
+		Port:              9000,
+		Version:           "1.0",
+		Bots:              1,
+		PasswordProtected: true,
+		Keywords:          []string{"a", "b"},
+		SteamID:           2,
+		SourceTVPort:      9001,
+		SourceTVName:      "tv",
+		GameID:            3,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	clientAddr := "my-client:1234"
+
+	// Query packet to receive challenge
+	qResp, err := q.Respond(clientAddr, a2sInfoRequest)
+	require.NoError(t, err)
+	require.Equal(t, s2cChallengeResponse, qResp[0:5])
+
+	resp, err := q.Respond(clientAddr, bytes.Join([][]byte{a2sInfoRequest, {0x0}, qResp[5:9]}, nil))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{0xFF, 0xFF, 0xFF, 0xFF, 0x49},
+				{1},
+				[]byte("foo\x00"),
+				[]byte("map\x00"),
+				[]byte("n/a\x00"),
+				[]byte("type\x00"),
+				{0, 0},
+				{1},
+				{2},
+				{1},
+				{'d'},
+				{environmentFromRuntime(runtime.GOOS)},
+				{1},
+				{0},
+				[]byte("1.0\x00"),
+				{0xF1},
+				{0x28, 0x23},             // game port
+				{2, 0, 0, 0, 0, 0, 0, 0}, // steam ID
+				{0x29, 0x23},             // SourceTV port
+				[]byte("tv\x00"),
+				[]byte("a,b\x00"),
+				{3, 0, 0, 0, 0, 0, 0, 0}, // game ID
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_Player(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		Players: []proto.QueryPlayer{
+			{Name: "foo", Score: -1},
+			{Name: "bar", Score: 10, JoinedAt: time.Now().Add(-1 * time.Hour)},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	clientAddr := "my-client:1234"
+
+	// Query packet to receive challenge
+	qResp, err := q.Respond(clientAddr, bytes.Join([][]byte{a2sPlayerRequest, {0xFF, 0xFF, 0xFF, 0xFF}}, nil))
+	require.NoError(t, err)
+	require.Equal(t, s2cChallengeResponse, qResp[0:5])
+
+	// Query packet with challenge included
+	resp, err := q.Respond(clientAddr, bytes.Join([][]byte{a2sPlayerRequest, qResp[5:9]}, nil))
+	require.NoError(t, err)
+	require.Equal(t, a2sPlayerResponse, resp[0:5])
+	require.Equal(t, byte(2), resp[5])
+
+	r := bytes.NewBuffer(resp[6:])
+
+	// First player has no join time.
+	require.Equal(t, byte(0), mustReadByte(t, r))
+	require.Equal(t, "foo\x00", string(r.Next(4)))
+	var score int32
+	var duration float32
+	require.NoError(t, binary.Read(r, binary.LittleEndian, &score))
+	require.NoError(t, binary.Read(r, binary.LittleEndian, &duration))
+	require.Equal(t, int32(-1), score)
+	require.Equal(t, float32(0), duration)
+
+	// Second player has been connected for an hour.
+	require.Equal(t, byte(1), mustReadByte(t, r))
+	require.Equal(t, "bar\x00", string(r.Next(4)))
+	require.NoError(t, binary.Read(r, binary.LittleEndian, &score))
+	require.NoError(t, binary.Read(r, binary.LittleEndian, &duration))
+	require.Equal(t, int32(10), score)
+	require.InDelta(t, 3600, duration, 5)
+	require.Equal(t, 0, r.Len())
+
+	// The challenge can only be used once.
+	_, err = q.Respond(clientAddr, bytes.Join([][]byte{a2sPlayerRequest, qResp[5:9]}, nil))
+	require.ErrorIs(t, err, proto.ErrNoChallenge)
+}
+
+func Test_Respond_Player_invalidLength(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{})
+	require.NoError(t, err)
+
+	_, err = q.Respond("my-client:1234", a2sPlayerRequest)
+	require.ErrorIs(t, err, errInvalidPacketLength)
+
+	_, err = q.Respond("my-client:1234", a2sPlayerRequest[0:4])
+	require.ErrorIs(t, err, errInvalidPacketLength)
+}
+
+func Test_Respond_Rules(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		Rules: map[string]any{
+			"sv_gravity":  800,
+			"mp_friendly": true,
+			"mode":        "ctf",
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	clientAddr := "my-client:1234"
+
+	// Query packet to receive challenge
+	qResp, err := q.Respond(clientAddr, bytes.Join([][]byte{a2sRulesRequest, {0xFF, 0xFF, 0xFF, 0xFF}}, nil))
+	require.NoError(t, err)
+	require.Equal(t, s2cChallengeResponse, qResp[0:5])
+
+	// Query packet with challenge included
+	resp, err := q.Respond(clientAddr, bytes.Join([][]byte{a2sRulesRequest, qResp[5:9]}, nil))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				a2sRulesResponse,
+				{3, 0},
+				[]byte("mode\x00ctf\x00"),
+				[]byte("mp_friendly\x001\x00"),
+				[]byte("sv_gravity\x00800\x00"),
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_cached(t *testing.T) {
+	t.Parallel()
+	state := proto.NewQueryStatePointer(&proto.QueryState{
+		Rules: map[string]any{"mode": "ctf"},
+	})
+	q, err := NewQueryResponderFromSnapshot(state)
+	require.NoError(t, err)
+	require.NoError(t, q.EnableStatelessChallenges())
+
+	// query sends a challenge request, followed by request with the challenge appended.
+	query := func(challengeRequest []byte, request []byte) []byte {
+		challenge, err := q.Respond("my-client:1234", challengeRequest)
+		require.NoError(t, err)
+
+		resp, err := q.Respond("my-client:1234", bytes.Join([][]byte{request, challenge[5:9]}, nil))
+		require.NoError(t, err)
+		return resp
+	}
+	queryInfo := func() []byte {
+		return query(a2sInfoRequest, bytes.Join([][]byte{a2sInfoRequest, {0}}, nil))
+	}
+	queryRules := func() []byte {
+		return query(bytes.Join([][]byte{a2sRulesRequest, {0xFF, 0xFF, 0xFF, 0xFF}}, nil), a2sRulesRequest)
+	}
+
+	info := queryInfo()
+	rules := queryRules()
+	require.Equal(t, info, queryInfo())
+	require.Equal(t, rules, queryRules())
+
+	// Cached responses are replaced once the snapshot is replaced, even if its revision is unchanged.
+	state.Store(&proto.QueryState{
+		ServerName: "foo",
+		Rules:      map[string]any{"mode": "dm"},
+	})
+	require.NotEqual(t, info, queryInfo())
+	require.Equal(t, bytes.Join([][]byte{a2sRulesResponse, {1, 0}, []byte("mode\x00dm\x00")}, nil), queryRules())
+}
+
+func Test_Respond_stateModified(t *testing.T) {
+	t.Parallel()
+	state := &proto.QueryState{
+		ServerName: "foo",
+		Rules:      map[string]any{"mode": "ctf"},
+	}
+	q, err := NewQueryResponder(state)
+	require.NoError(t, err)
+
+	query := func(challengeRequest []byte, request []byte) []byte {
+		challenge, err := q.Respond("my-client:1234", challengeRequest)
+		require.NoError(t, err)
+
+		resp, err := q.Respond("my-client:1234", bytes.Join([][]byte{request, challenge[5:9]}, nil))
+		require.NoError(t, err)
+		return resp
+	}
+	queryInfo := func() []byte {
+		return query(a2sInfoRequest, bytes.Join([][]byte{a2sInfoRequest, {0}}, nil))
+	}
+	queryRules := func() []byte {
+		return query(bytes.Join([][]byte{a2sRulesRequest, {0xFF, 0xFF, 0xFF, 0xFF}}, nil), a2sRulesRequest)
+	}
+
+	require.Contains(t, string(queryInfo()), "foo")
+	require.Contains(t, string(queryRules()), "ctf")
+
+	// Responses are not cached, so modifications to the state are seen by the next query.
+	state.ServerName = "bar"
+	state.Rules["mode"] = "dm"
+	require.Contains(t, string(queryInfo()), "bar")
+	require.Equal(t, bytes.Join([][]byte{a2sRulesResponse, {1, 0}, []byte("mode\x00dm\x00")}, nil), queryRules())
+}
+
+func Test_RespondPackets_split(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		Rules: map[string]any{
+			"a": strings.Repeat("a", 100),
+			"b": strings.Repeat("b", 100),
+		},
+	})
+	require.NoError(t, err)
+
+	clientAddr := "my-client:1234"
+
+	qResp, err := q.RespondPackets(clientAddr, bytes.Join([][]byte{a2sRulesRequest, {0xFF, 0xFF, 0xFF, 0xFF}}, nil), 100)
+	require.NoError(t, err)
+	require.Len(t, qResp, 1)
+	require.Equal(t, s2cChallengeResponse, qResp[0][0:5])
+
+	packets, err := q.RespondPackets(clientAddr, bytes.Join([][]byte{a2sRulesRequest, qResp[0][5:9]}, nil), 100)
+	require.NoError(t, err)
+
+	// 7 byte header, 2 * (2 byte name + 101 byte value) = 213 bytes, split into 88 byte payloads.
+	require.Len(t, packets, 3)
+
+	reassembled := bytes.NewBuffer(nil)
+	for i, p := range packets {
+		require.LessOrEqual(t, len(p), 100)
+		require.Equal(t, splitResponseHeader, p[0:4])
+		require.Equal(t, packets[0][4:8], p[4:8]) // ID
+		require.Equal(t, byte(3), p[8])           // Total
+		require.Equal(t, byte(i), p[9])           // Number
+		require.Equal(t, []byte{100, 0}, p[10:12])
+		reassembled.Write(p[12:])
+	}
+
+	require.Equal(t, a2sRulesResponse, reassembled.Bytes()[0:5])
+	require.Equal(t, 213, reassembled.Len())
+}
+
+func Test_split_errors(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{})
+	require.NoError(t, err)
+
+	_, err = q.split(make([]byte, 20), splitHeaderSize)
+	require.ErrorIs(t, err, errPacketSizeTooSmall)
+
+	_, err = q.split(make([]byte, 1000), splitHeaderSize+1)
+	require.ErrorIs(t, err, errResponseTooLarge)
+}
+
+func mustReadByte(t *testing.T, r *bytes.Buffer) byte {
+	t.Helper()
+	b, err := r.ReadByte()
+	require.NoError(t, err)
+	return b
+}
+
 func Test_environmentFromRuntime(t *testing.T) {
 	t.Parallel()
 
@@ -87,7 +382,7 @@ func Test_parseInfoRequest(t *testing.T) {
 	tests := []struct {
 		name          string
 		input         []byte
-		expected      *infoRequest
+		expected      infoRequest
 		expectedError error
 	}{
 		{
@@ -100,8 +395,8 @@ func Test_parseInfoRequest(t *testing.T) {
 				},
 				nil,
 			),
-			expected: &infoRequest{
-				Payload:   "hello payload",
+			expected: infoRequest{
+				Payload:   []byte("hello payload"),
 				Challenge: 1,
 			},
 		},
@@ -113,7 +408,7 @@ func Test_parseInfoRequest(t *testing.T) {
 				},
 				nil,
 			),
-			expected: &infoRequest{},
+			expected: infoRequest{},
 		},
 		{
 			name: "one byte payload",
@@ -124,7 +419,7 @@ func Test_parseInfoRequest(t *testing.T) {
 				},
 				nil,
 			),
-			expected: &infoRequest{},
+			expected: infoRequest{},
 		},
 		{
 			name: "no challenge",
@@ -135,8 +430,8 @@ func Test_parseInfoRequest(t *testing.T) {
 				},
 				nil,
 			),
-			expected: &infoRequest{
-				Payload: "hello payload",
+			expected: infoRequest{
+				Payload: []byte("hello payload"),
 			},
 		},
 		{
@@ -148,8 +443,8 @@ func Test_parseInfoRequest(t *testing.T) {
 				},
 				nil,
 			),
-			expected: &infoRequest{
-				Payload: "hello payload",
+			expected: infoRequest{
+				Payload: []byte("hello payload"),
 			},
 		},
 		{
@@ -173,3 +468,60 @@ func Test_parseInfoRequest(t *testing.T) {
 		})
 	}
 }
+
+func Test_AppendResponse(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     2,
+		ServerName:     "foo",
+	})
+	require.NoError(t, err)
+
+	// Stateless challenges can be satisfied more than once.
+	require.NoError(t, q.EnableStatelessChallenges())
+
+	challenge, err := q.Respond("", a2sInfoRequest)
+	require.NoError(t, err)
+
+	req := append(append([]byte{}, a2sInfoRequest...), challenge[5:9]...)
+	expected, err := q.Respond("", req)
+	require.NoError(t, err)
+
+	// The response is appended to, rather than overwriting, the provided buffer.
+	prefix := []byte{0xAA, 0xBB}
+	resp, err := q.AppendResponse(append([]byte{}, prefix...), "", req)
+	require.NoError(t, err)
+	require.Equal(t, append(prefix, expected...), resp)
+}
+
+func Benchmark_AppendResponse(b *testing.B) {
+	q, err := NewQueryResponderFromSnapshot(proto.NewQueryStatePointer(&proto.QueryState{
+		CurrentPlayers: 2,
+		MaxPlayers:     16,
+		ServerName:     "Benchmark Server",
+		GameType:       "Deathmatch",
+		Map:            "de_dust2",
+		Port:           9000,
+		Version:        "1.0.0",
+		Keywords:       []string{"casual", "eu"},
+	}))
+	require.NoError(b, err)
+
+	require.NoError(b, q.EnableStatelessChallenges())
+
+	challenge, err := q.Respond("", a2sInfoRequest)
+	require.NoError(b, err)
+
+	req := append(append([]byte{}, a2sInfoRequest...), challenge[5:9]...)
+	dst := make([]byte, 0, 1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err = q.AppendResponse(dst[:0], "", req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
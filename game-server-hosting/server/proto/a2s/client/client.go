@@ -0,0 +1,252 @@
+// Package client implements a client for the A2S query protocol, which performs the challenge handshake, reassembles
+// split responses and decodes responses into Go types.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+type (
+	// Client represents a client which queries a single server using the A2S protocol. It is safe for concurrent
+	// use, although queries are performed one at a time.
+	Client struct {
+		conn    net.Conn
+		timeout time.Duration
+
+		// mtx ensures only one query is in flight at a time, so that responses cannot be mismatched.
+		mtx sync.Mutex
+		buf []byte
+	}
+
+	// Option represents a function that modifies a property of the client.
+	Option func(c *Client)
+)
+
+const (
+	// DefaultTimeout is the default time allowed for a query to complete, if the context has no deadline.
+	DefaultTimeout = 5 * time.Second
+
+	// maxPacketSize is the size of the buffer packets are read into.
+	maxPacketSize = 64 * 1024
+
+	// splitHeaderSize is the size of the header of each packet in a split response.
+	splitHeaderSize = 12
+)
+
+const (
+	// Types of response, which follow the single packet header.
+	challengeResponseType = byte(0x41)
+	infoResponseType      = byte(0x49)
+	playerResponseType    = byte(0x44)
+	rulesResponseType     = byte(0x45)
+)
+
+var (
+	singleResponseHeader = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	splitResponseHeader  = []byte{0xFE, 0xFF, 0xFF, 0xFF}
+
+	infoRequest   = []byte("\xFF\xFF\xFF\xFFTSource Engine Query\x00")
+	playerRequest = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x55}
+	rulesRequest  = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x56}
+
+	// noChallenge is the challenge value sent to request a challenge.
+	noChallenge = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+// WithTimeout sets the time allowed for a query to complete, if the context has no deadline.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// New creates a new client which queries the server at address, in the form "host:port".
+func New(address string, opts ...Option) (*Client, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:    conn,
+		timeout: DefaultTimeout,
+		buf:     make([]byte, maxPacketSize),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Info performs an A2S_INFO query. If the query does not complete before the deadline of ctx, or the client timeout
+// if ctx has no deadline, an error wrapping context.DeadlineExceeded is returned.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	// Some servers answer A2S_INFO requests without a challenge, so one is only sent if requested.
+	resp, err := c.query(ctx, infoRequest, infoRequest, infoResponseType)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeInfo(resp)
+}
+
+// Players performs an A2S_PLAYER query. Errors are as per Info.
+func (c *Client) Players(ctx context.Context) ([]Player, error) {
+	resp, err := c.query(ctx, bytes.Join([][]byte{playerRequest, noChallenge}, nil), playerRequest, playerResponseType)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePlayers(resp)
+}
+
+// Rules performs an A2S_RULES query. Errors are as per Info.
+func (c *Client) Rules(ctx context.Context) (map[string]string, error) {
+	resp, err := c.query(ctx, bytes.Join([][]byte{rulesRequest, noChallenge}, nil), rulesRequest, rulesResponseType)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeRules(resp)
+}
+
+// query sends the initial request and, if the server responds with a challenge, sends the challenge request with the
+// challenge appended. The payload of the response of the expected type is returned, excluding its header.
+func (c *Client) query(ctx context.Context, initial []byte, challengeRequest []byte, responseType byte) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	req := initial
+	for challenged := false; ; {
+		if _, err := c.conn.Write(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.readResponse(ctx, responseType)
+		if err != nil {
+			return nil, err
+		}
+
+		// Single packet responses are held in the read buffer, so are copied before the lock is released.
+		if resp[0] == responseType {
+			return append([]byte(nil), resp[1:]...), nil
+		}
+
+		// The server responded with a challenge. Only one is expected.
+		if challenged || len(resp) != 5 {
+			return nil, ErrInvalidResponse
+		}
+
+		challenged = true
+		req = bytes.Join([][]byte{challengeRequest, resp[1:5]}, nil)
+	}
+}
+
+// readResponse reads packets until a challenge or a response of the expected type is received, reassembling split
+// responses. The returned response excludes the single packet header.
+func (c *Client) readResponse(ctx context.Context, responseType byte) ([]byte, error) {
+	var (
+		splitID  uint32
+		parts    [][]byte
+		received int
+	)
+
+	for {
+		packet, err := c.read(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp []byte
+		switch {
+		case bytes.HasPrefix(packet, singleResponseHeader):
+			resp = packet[len(singleResponseHeader):]
+
+		case bytes.HasPrefix(packet, splitResponseHeader):
+			if len(packet) < splitHeaderSize {
+				return nil, ErrInvalidResponse
+			}
+
+			id := binary.LittleEndian.Uint32(packet[4:8])
+			total, number := int(packet[8]), int(packet[9])
+			if total == 0 || number >= total {
+				return nil, ErrInvalidResponse
+			}
+
+			if id&0x80000000 != 0 {
+				return nil, ErrCompressedResponse
+			}
+
+			// A new split response replaces any partially received one.
+			if parts == nil || id != splitID || len(parts) != total {
+				splitID, parts, received = id, make([][]byte, total), 0
+			}
+
+			if parts[number] == nil {
+				parts[number] = append([]byte{}, packet[splitHeaderSize:]...)
+				received++
+			}
+
+			if received < total {
+				continue
+			}
+
+			full := bytes.Join(parts, nil)
+			if !bytes.HasPrefix(full, singleResponseHeader) {
+				return nil, ErrInvalidResponse
+			}
+
+			resp = full[len(singleResponseHeader):]
+
+		default:
+			return nil, ErrInvalidResponse
+		}
+
+		// Ignore any late responses to previous queries.
+		if len(resp) > 0 && (resp[0] == responseType || resp[0] == challengeResponseType) {
+			return resp, nil
+		}
+	}
+}
+
+// read reads a single packet from the server.
+func (c *Client) read(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	n, err := c.conn.Read(c.buf)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+		}
+
+		return nil, err
+	}
+
+	return c.buf[:n], nil
+}
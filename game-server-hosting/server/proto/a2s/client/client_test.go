@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/a2s"
+	"github.com/stretchr/testify/require"
+)
+
+// serve responds to queries on a local UDP endpoint with the provided responder, splitting responses larger than
+// maxPacketSize bytes. The endpoint address is returned.
+func serve(t *testing.T, r *a2s.QueryResponder, maxPacketSize int) string {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			packets, err := r.RespondPackets(addr.String(), buf[:n], maxPacketSize)
+			if err != nil {
+				continue
+			}
+
+			for _, p := range packets {
+				if _, err = conn.WriteToUDP(p, addr); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func Test_Info(t *testing.T) {
+	t.Parallel()
+
+	r, err := a2s.NewQueryResponder(&proto.QueryState{
+		CurrentPlayers:    2,
+		MaxPlayers:        8,
+		ServerName:        "foo",
+		GameType:          "ctf",
+		Map:               "bar",
+		Version:           "1.0.0",
+		Bots:              1,
+		PasswordProtected: true,
+		Port:              9000,
+		Keywords:          []string{"a", "b"},
+		GameID:            730,
+	})
+	require.NoError(t, err)
+
+	c, err := New(serve(t, r, 1400), WithTimeout(time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+
+	info, err := c.Info(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "foo", info.ServerName)
+	require.Equal(t, "bar", info.Map)
+	require.Equal(t, "ctf", info.Game)
+	require.Equal(t, byte(2), info.Players)
+	require.Equal(t, byte(8), info.MaxPlayers)
+	require.Equal(t, byte(1), info.Bots)
+	require.True(t, info.PasswordProtected)
+	require.Equal(t, "1.0.0", info.Version)
+	require.Equal(t, uint16(9000), info.Port)
+	require.Equal(t, []string{"a", "b"}, info.Keywords)
+	require.Equal(t, uint64(730), info.GameID)
+	require.Zero(t, info.SteamID)
+}
+
+func Test_Players_Rules(t *testing.T) {
+	t.Parallel()
+
+	r, err := a2s.NewQueryResponder(&proto.QueryState{
+		Players: []proto.QueryPlayer{
+			{Name: "alice", Score: -1, JoinedAt: time.Now().Add(-time.Minute)},
+			{Name: "bob", Score: 5},
+		},
+		Rules: map[string]any{"mode": "ctf", "round": 2},
+	})
+	require.NoError(t, err)
+
+	c, err := New(serve(t, r, 1400))
+	require.NoError(t, err)
+	defer c.Close()
+
+	players, err := c.Players(context.Background())
+	require.NoError(t, err)
+	require.Len(t, players, 2)
+	require.Equal(t, "alice", players[0].Name)
+	require.Equal(t, int32(-1), players[0].Score)
+	require.InDelta(t, time.Minute, players[0].Duration, float64(10*time.Second))
+	require.Equal(t, Player{Index: 1, Name: "bob", Score: 5}, players[1])
+
+	rules, err := c.Rules(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"mode": "ctf", "round": "2"}, rules)
+}
+
+func Test_concurrent(t *testing.T) {
+	t.Parallel()
+
+	r, err := a2s.NewQueryResponder(&proto.QueryState{
+		ServerName: "foo",
+		Map:        "bar",
+		Players: []proto.QueryPlayer{
+			{Name: "alice", Score: 1},
+			{Name: "bob", Score: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	c, err := New(serve(t, r, 1400))
+	require.NoError(t, err)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			info, err := c.Info(context.Background())
+			if err == nil && info.ServerName != "foo" {
+				err = fmt.Errorf("unexpected server name %q", info.ServerName)
+			}
+			errs <- err
+		}()
+
+		go func() {
+			defer wg.Done()
+			players, err := c.Players(context.Background())
+			if err == nil && len(players) != 2 {
+				err = fmt.Errorf("unexpected players %v", players)
+			}
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func Test_Rules_split(t *testing.T) {
+	t.Parallel()
+
+	rules := map[string]any{}
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		rules[k] = "some long rule value"
+	}
+
+	r, err := a2s.NewQueryResponder(&proto.QueryState{Rules: rules})
+	require.NoError(t, err)
+
+	// Responses are split into packets of at most 32 bytes.
+	c, err := New(serve(t, r, 32))
+	require.NoError(t, err)
+	defer c.Close()
+
+	actual, err := c.Rules(context.Background())
+	require.NoError(t, err)
+	require.Len(t, actual, len(rules))
+	require.Equal(t, "some long rule value", actual["h"])
+}
+
+func Test_Info_timeout(t *testing.T) {
+	t.Parallel()
+
+	// Nothing responds on this endpoint.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c, err := New(conn.LocalAddr().String(), WithTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Info(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_decode_invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := decodeInfo([]byte{1, 'f', 'o', 'o'})
+	require.ErrorIs(t, err, ErrInvalidResponse)
+
+	_, err = decodePlayers([]byte{1, 0})
+	require.ErrorIs(t, err, ErrInvalidResponse)
+
+	_, err = decodeRules([]byte{1, 0, 'a', 0})
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
@@ -0,0 +1,14 @@
+package client
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidResponse is an error which specifies the server responded with a malformed packet.
+	ErrInvalidResponse = errors.New("invalid response")
+
+	// ErrCompressedResponse is an error which specifies the server responded with a compressed split response,
+	// which is not supported.
+	ErrCompressedResponse = errors.New("compressed responses are not supported")
+)
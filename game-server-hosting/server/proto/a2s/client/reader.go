@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// reader reads values in the A2S wire format from a buffer. Once a read fails, all subsequent reads return zero
+// values and err is set.
+type reader struct {
+	buf []byte
+	err error
+}
+
+// next returns the next n bytes of the buffer.
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if len(r.buf) < n {
+		r.err = ErrInvalidResponse
+		return nil
+	}
+
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// byte reads a single byte.
+func (r *reader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+
+	return 0
+}
+
+// uint16 reads a little-endian uint16.
+func (r *reader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+
+	return 0
+}
+
+// uint32 reads a little-endian uint32.
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+
+	return 0
+}
+
+// uint64 reads a little-endian uint64.
+func (r *reader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+
+	return 0
+}
+
+// string reads a NUL-terminated string.
+func (r *reader) string() string {
+	if r.err != nil {
+		return ""
+	}
+
+	n := bytes.IndexByte(r.buf, 0)
+	if n < 0 {
+		r.err = ErrInvalidResponse
+		return ""
+	}
+
+	s := string(r.buf[:n])
+	r.buf = r.buf[n+1:]
+	return s
+}
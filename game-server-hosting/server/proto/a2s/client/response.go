@@ -0,0 +1,145 @@
+package client
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+type (
+	// Info represents an A2S_INFO response. Fields following Version are only set if present in the response.
+	Info struct {
+		Protocol          byte
+		ServerName        string
+		Map               string
+		Folder            string
+		Game              string
+		AppID             uint16
+		Players           byte
+		MaxPlayers        byte
+		Bots              byte
+		ServerType        byte
+		Environment       byte
+		PasswordProtected bool
+		VACSecured        bool
+		Version           string
+
+		Port         uint16
+		SteamID      uint64
+		SourceTVPort uint16
+		SourceTVName string
+		Keywords     []string
+		GameID       uint64
+	}
+
+	// Player represents a single player in an A2S_PLAYER response.
+	Player struct {
+		Index    byte
+		Name     string
+		Score    int32
+		Duration time.Duration
+	}
+)
+
+// Extra Data Flags, which denote the optional fields present in an A2S_INFO response.
+const (
+	edfGamePort = byte(0x80)
+	edfSteamID  = byte(0x10)
+	edfSourceTV = byte(0x40)
+	edfKeywords = byte(0x20)
+	edfGameID   = byte(0x01)
+)
+
+// decodeInfo decodes the payload of an A2S_INFO response.
+func decodeInfo(payload []byte) (*Info, error) {
+	r := &reader{buf: payload}
+	info := &Info{
+		Protocol:          r.byte(),
+		ServerName:        r.string(),
+		Map:               r.string(),
+		Folder:            r.string(),
+		Game:              r.string(),
+		AppID:             r.uint16(),
+		Players:           r.byte(),
+		MaxPlayers:        r.byte(),
+		Bots:              r.byte(),
+		ServerType:        r.byte(),
+		Environment:       r.byte(),
+		PasswordProtected: r.byte() == 1,
+		VACSecured:        r.byte() == 1,
+		Version:           r.string(),
+	}
+
+	if r.err == nil && len(r.buf) > 0 {
+		edf := r.byte()
+
+		if edf&edfGamePort != 0 {
+			info.Port = r.uint16()
+		}
+
+		if edf&edfSteamID != 0 {
+			info.SteamID = r.uint64()
+		}
+
+		if edf&edfSourceTV != 0 {
+			info.SourceTVPort = r.uint16()
+			info.SourceTVName = r.string()
+		}
+
+		if edf&edfKeywords != 0 {
+			if keywords := r.string(); keywords != "" {
+				info.Keywords = strings.Split(keywords, ",")
+			}
+		}
+
+		if edf&edfGameID != 0 {
+			info.GameID = r.uint64()
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return info, nil
+}
+
+// decodePlayers decodes the payload of an A2S_PLAYER response.
+func decodePlayers(payload []byte) ([]Player, error) {
+	r := &reader{buf: payload}
+	count := int(r.byte())
+
+	players := make([]Player, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		players = append(players, Player{
+			Index:    r.byte(),
+			Name:     r.string(),
+			Score:    int32(r.uint32()),
+			Duration: time.Duration(float64(math.Float32frombits(r.uint32())) * float64(time.Second)),
+		})
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return players, nil
+}
+
+// decodeRules decodes the payload of an A2S_RULES response.
+func decodeRules(payload []byte) (map[string]string, error) {
+	r := &reader{buf: payload}
+	count := int(r.uint16())
+
+	rules := make(map[string]string, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		k := r.string()
+		rules[k] = r.string()
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return rules, nil
+}
@@ -12,8 +12,19 @@ type (
 	}
 )
 
-// errNotAnInfoRequest defines an error in which the input is not an A2S_INFO request.
-var errNotAnInfoRequest = errors.New("not an info request")
+var (
+	// errNotAnInfoRequest defines an error in which the input is not an A2S_INFO request.
+	errNotAnInfoRequest = errors.New("not an info request")
+
+	// errInvalidPacketLength defines an error in which the input is too short for the request type.
+	errInvalidPacketLength = errors.New("invalid packet length")
+
+	// errPacketSizeTooSmall defines an error in which the maximum packet size cannot fit a split packet header.
+	errPacketSizeTooSmall = errors.New("maximum packet size is too small to split response")
+
+	// errResponseTooLarge defines an error in which a response cannot be split into few enough packets.
+	errResponseTooLarge = errors.New("response is too large to split")
+)
 
 // NewUnsupportedQueryError returns a new instance of UnsupportedQueryError.
 func NewUnsupportedQueryError(header []byte) error {
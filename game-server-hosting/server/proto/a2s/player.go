@@ -0,0 +1,103 @@
+package a2s
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+type (
+	// playerHeaderWireFormat describes the format of the header of an A2S_PLAYER query response.
+	playerHeaderWireFormat struct {
+		Header      []byte
+		PlayerCount uint8
+	}
+
+	// playerWireFormat describes the format of a single player in an A2S_PLAYER query response.
+	playerWireFormat struct {
+		Index    uint8
+		Name     string
+		Score    int32
+		Duration float32
+	}
+)
+
+var (
+	a2sPlayerRequest  = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x55}
+	a2sPlayerResponse = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x44}
+)
+
+// handlePlayerRequest handles an incoming A2S_PLAYER request.
+func (q *QueryResponder) handlePlayerRequest(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
+	challenge, err := parseChallengeRequest(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// If no challenge has been supplied, respond with one. Expect it on the next request.
+	if challenge == 0 || challenge == noChallenge {
+		return q.challengeResponse(dst, clientAddress)
+	}
+
+	if err = q.ChallengeMatchesForClient(clientAddress, challenge); err != nil {
+		return nil, err
+	}
+
+	var players []proto.QueryPlayer
+	if qs := q.Snapshot(); qs != nil {
+		players = qs.Players
+	}
+
+	// The player count is a single byte, so only the first 255 players can be reported.
+	if len(players) > 0xFF {
+		players = players[:0xFF]
+	}
+
+	dst = playerHeaderWireFormat{
+		Header:      a2sPlayerResponse,
+		PlayerCount: uint8(len(players)),
+	}.AppendTo(dst)
+
+	now := time.Now()
+	for i, p := range players {
+		var duration float32
+		if !p.JoinedAt.IsZero() {
+			duration = float32(now.Sub(p.JoinedAt).Seconds())
+		}
+
+		dst = playerWireFormat{
+			Index:    uint8(i),
+			Name:     p.Name,
+			Score:    p.Score,
+			Duration: duration,
+		}.AppendTo(dst)
+	}
+
+	return dst, nil
+}
+
+// AppendTo appends the A2S_PLAYER response header in the A2S wire format to dst.
+func (h playerHeaderWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, h.Header...)
+	return append(dst, h.PlayerCount)
+}
+
+// AppendTo appends the player in the A2S wire format to dst.
+func (p playerWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, p.Index)
+	dst = appendString(dst, p.Name)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(p.Score))
+	return binary.LittleEndian.AppendUint32(dst, math.Float32bits(p.Duration))
+}
+
+// parseChallengeRequest parses the challenge from an incoming request which consists only of a header and
+// a challenge, such as A2S_PLAYER.
+func parseChallengeRequest(buf []byte) (uint32, error) {
+	if len(buf) < 9 {
+		return 0, errInvalidPacketLength
+	}
+
+	return binary.LittleEndian.Uint32(buf[5:9]), nil
+}
@@ -0,0 +1,106 @@
+package a2s
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+type (
+	// rulesHeaderWireFormat describes the format of the header of an A2S_RULES query response.
+	rulesHeaderWireFormat struct {
+		Header    []byte
+		RuleCount uint16
+	}
+
+	// ruleWireFormat describes the format of a single rule in an A2S_RULES query response.
+	ruleWireFormat struct {
+		Name  string
+		Value string
+	}
+)
+
+var (
+	a2sRulesRequest  = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x56}
+	a2sRulesResponse = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x45}
+)
+
+// handleRulesRequest handles an incoming A2S_RULES request.
+func (q *QueryResponder) handleRulesRequest(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
+	challenge, err := parseChallengeRequest(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// If no challenge has been supplied, respond with one. Expect it on the next request.
+	if challenge == 0 || challenge == noChallenge {
+		return q.challengeResponse(dst, clientAddress)
+	}
+
+	if err = q.ChallengeMatchesForClient(clientAddress, challenge); err != nil {
+		return nil, err
+	}
+
+	return q.appendCached(dst, cacheKeyRules, appendRules), nil
+}
+
+// appendRules appends an A2S_RULES response for the provided query state to dst. Rules are ordered by their key.
+func appendRules(dst []byte, qs *proto.QueryState) []byte {
+	var rules map[string]any
+	if qs != nil {
+		rules = qs.Rules
+	}
+
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	dst = rulesHeaderWireFormat{
+		Header:    a2sRulesResponse,
+		RuleCount: uint16(len(keys)),
+	}.AppendTo(dst)
+
+	for _, k := range keys {
+		dst = ruleWireFormat{
+			Name:  k,
+			Value: ruleValueString(rules[k]),
+		}.AppendTo(dst)
+	}
+
+	return dst
+}
+
+// AppendTo appends the A2S_RULES response header in the A2S wire format to dst.
+func (h rulesHeaderWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, h.Header...)
+	return binary.LittleEndian.AppendUint16(dst, h.RuleCount)
+}
+
+// AppendTo appends the rule in the A2S wire format to dst.
+func (r ruleWireFormat) AppendTo(dst []byte) []byte {
+	dst = appendString(dst, r.Name)
+	return appendString(dst, r.Value)
+}
+
+// ruleValueString formats a rule value as a string. Booleans are formatted as "1" or "0", as is conventional for
+// A2S rules.
+func ruleValueString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	}
+
+	return fmt.Sprint(v)
+}
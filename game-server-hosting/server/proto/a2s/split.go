@@ -0,0 +1,92 @@
+package a2s
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
+
+type (
+	// splitWireFormat describes the header of a single packet in a split (multi-packet) response.
+	splitWireFormat struct {
+		Header     []byte
+		ID         uint32
+		Total      byte
+		Number     byte
+		PacketSize uint16
+	}
+)
+
+const (
+	// splitHeaderSize is the size, in bytes, of the header of each packet in a split response.
+	splitHeaderSize = 12
+
+	// maxSplitPackets is the maximum number of packets a split response can be made up of.
+	maxSplitPackets = 0xFF
+)
+
+var splitResponseHeader = []byte{0xFE, 0xFF, 0xFF, 0xFF}
+
+// RespondPackets responds to a query in the A2S wire protocol, splitting the response into multiple packets in the
+// Source multi-packet format if it exceeds maxPacketSize bytes.
+func (q *QueryResponder) RespondPackets(clientAddress string, buf []byte, maxPacketSize int) ([][]byte, error) {
+	resp, err := q.Respond(clientAddress, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.split(resp, maxPacketSize)
+}
+
+// SplitResponse splits a response into packets in the Source multi-packet format if it exceeds maxPacketSize bytes.
+func (q *QueryResponder) SplitResponse(resp []byte, maxPacketSize int) ([][]byte, error) {
+	return q.split(resp, maxPacketSize)
+}
+
+// split splits the response into packets no larger than maxPacketSize bytes. Responses which fit into a single packet
+// are returned as-is.
+func (q *QueryResponder) split(resp []byte, maxPacketSize int) ([][]byte, error) {
+	if len(resp) <= maxPacketSize {
+		return [][]byte{resp}, nil
+	}
+
+	payloadSize := maxPacketSize - splitHeaderSize
+	if payloadSize <= 0 {
+		return nil, errPacketSizeTooSmall
+	}
+
+	total := (len(resp) + payloadSize - 1) / payloadSize
+	if total > maxSplitPackets {
+		return nil, errResponseTooLarge
+	}
+
+	// The high bit of the ID indicates a compressed response, which is not supported.
+	id := atomic.AddUint32(&q.splitID, 1) & 0x7FFFFFFF
+
+	packets := make([][]byte, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * payloadSize
+		if end > len(resp) {
+			end = len(resp)
+		}
+
+		packet := splitWireFormat{
+			Header:     splitResponseHeader,
+			ID:         id,
+			Total:      byte(total),
+			Number:     byte(i),
+			PacketSize: uint16(maxPacketSize),
+		}.AppendTo(make([]byte, 0, splitHeaderSize+end-i*payloadSize))
+
+		packets = append(packets, append(packet, resp[i*payloadSize:end]...))
+	}
+
+	return packets, nil
+}
+
+// AppendTo appends the split packet header in the A2S wire format to dst.
+func (s splitWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, s.Header...)
+	dst = binary.LittleEndian.AppendUint32(dst, s.ID)
+	dst = append(dst, s.Total, s.Number)
+	return binary.LittleEndian.AppendUint16(dst, s.PacketSize)
+}
@@ -1,10 +1,15 @@
 package proto
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/binary"
 	"errors"
+	"hash"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -12,11 +17,34 @@ type (
 	// QueryBase is the base querying struct which can be used by other implementers to handle features such as
 	// challenge generation.
 	QueryBase struct {
-		challenges               sync.Map
-		challengesLastPurgedUTC  time.Time
-		challengesLastPurgedLock sync.RWMutex
+		challenges sync.Map
 
+		// nextPurgeUnixNano holds the time, in Unix nanoseconds, at which stale entries are next purged.
+		nextPurgeUnixNano atomic.Int64
+
+		// purging is set while a purge of stale entries is in progress.
+		purging atomic.Bool
+
+		// statelessSecret is the secret challenges are derived from when stateless challenges are enabled, and
+		// statelessMACs holds *statelessMAC values keyed with it.
+		statelessSecret []byte
+		statelessMACs   sync.Pool
+
+		// satisfied holds the expiry time, as an *atomic.Int64 of Unix nanoseconds, of the most recently satisfied
+		// challenge for each client address.
+		satisfied sync.Map
+
+		// ReuseChallenges allows a challenge to be satisfied more than once until it expires, for protocols whose
+		// clients reuse a challenge across several requests. By default, a challenge can only be satisfied once.
+		ReuseChallenges bool
+
+		// State holds the query state used to respond to queries if Snapshots is nil. It may be modified between
+		// queries, so responses using it must not be cached.
 		State *QueryState
+
+		// Snapshots holds the current snapshot of the query state. Snapshots are never modified once stored, so they
+		// can be read without locking, and responses may be cached until the snapshot is replaced.
+		Snapshots *atomic.Pointer[QueryState]
 	}
 
 	// challengeEntry represents an entry in the query responder challenges map.
@@ -24,6 +52,26 @@ type (
 		value     uint32
 		expiryUTC time.Time
 	}
+
+	// statelessMAC holds a keyed HMAC, along with buffers for its input and output, so that stateless challenges
+	// can be derived without allocating.
+	statelessMAC struct {
+		mac   hash.Hash
+		input []byte
+		sum   []byte
+	}
+)
+
+const (
+	// statelessChallengeInterval is the period of time each stateless challenge secret is used for. A challenge is
+	// accepted for the interval it was generated in and the one following it.
+	statelessChallengeInterval = 30 * time.Second
+
+	// statelessSecretSize is the size, in bytes, of the secret stateless challenges are derived from.
+	statelessSecretSize = 32
+
+	// challengePurgeInterval is the minimum period of time between purges of stale challenges.
+	challengePurgeInterval = 1 * time.Minute
 )
 
 var (
@@ -32,21 +80,63 @@ var (
 	ErrNoChallenge        = errors.New("no challenge")
 )
 
+// NewQueryStatePointer returns a pointer holding the provided query state snapshot, suitable for responders which
+// are not served by a Server.
+func NewQueryStatePointer(state *QueryState) *atomic.Pointer[QueryState] {
+	p := &atomic.Pointer[QueryState]{}
+	p.Store(state)
+	return p
+}
+
+// Snapshot returns the current snapshot of the query state held by Snapshots, or State if Snapshots is nil. The
+// snapshot must not be modified.
+func (q *QueryBase) Snapshot() *QueryState {
+	if q.Snapshots == nil {
+		return q.State
+	}
+
+	return q.Snapshots.Load()
+}
+
+// SetState replaces the query state used to respond to queries if Snapshots is nil. It must not be called while a
+// query is being answered.
+func (q *QueryBase) SetState(state *QueryState) {
+	q.State = state
+}
+
+// EnableStatelessChallenges switches challenge generation to a stateless mode, in which challenges are an HMAC of the
+// client address and a secret which rotates every 30 seconds. No state is stored per client, which bounds memory usage
+// under a flood of requests from spoofed addresses. Unlike the default mode, a challenge can be used more than once
+// until it expires. This must be called before the responder is in use.
+func (q *QueryBase) EnableStatelessChallenges() error {
+	secret := make([]byte, statelessSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+
+	q.statelessSecret = secret
+	q.statelessMACs.New = func() any {
+		return &statelessMAC{
+			mac: hmac.New(sha256.New, secret),
+			sum: make([]byte, 0, sha256.Size),
+		}
+	}
+
+	return nil
+}
+
 // GenerateChallenge generates a challenge value for the calling client address. If stale challenges have not been purged
 // for a while, this is done asynchronously.
 func (q *QueryBase) GenerateChallenge(clientAddress string) (uint32, error) {
-	// Purge entries asynchronously if we haven't done so in a while.
-	// Do this at the beginning so that any upcoming failures don't stop us from cleaning up.
-	q.challengesLastPurgedLock.RLock()
-	if time.Since(q.challengesLastPurgedUTC).Minutes() > 0 {
-		q.challengesLastPurgedLock.RUnlock()
-		q.challengesLastPurgedLock.Lock()
-		q.challengesLastPurgedUTC = time.Now().UTC()
-		q.challengesLastPurgedLock.Unlock()
-		q.challengesLastPurgedLock.RLock()
-		go q.purgeStaleChallenges(time.Now().UTC())
+	now := time.Now().UTC()
+
+	// Nothing is stored when generating stateless challenges, so there is nothing to purge.
+	if q.statelessSecret != nil {
+		return q.statelessChallenge(clientAddress, now), nil
 	}
-	q.challengesLastPurgedLock.RUnlock()
+
+	// Do this at the beginning so that any upcoming failures don't stop us from cleaning up.
+	q.purgeIfDue(now)
 
 	randBytes := make([]byte, 4)
 	if _, err := rand.Read(randBytes); err != nil {
@@ -56,7 +146,7 @@ func (q *QueryBase) GenerateChallenge(clientAddress string) (uint32, error) {
 	v := binary.BigEndian.Uint32(randBytes)
 	q.challenges.Store(clientAddress, challengeEntry{
 		value:     v,
-		expiryUTC: time.Now().UTC().Add(1 * time.Minute),
+		expiryUTC: now.Add(1 * time.Minute),
 	})
 
 	return v, nil
@@ -65,7 +155,55 @@ func (q *QueryBase) GenerateChallenge(clientAddress string) (uint32, error) {
 // ChallengeMatchesForClient determines whether the challenge value supplied by the client matches what is stored
 // by this server.
 func (q *QueryBase) ChallengeMatchesForClient(clientAddress string, challenge uint32) error {
-	expectedChallenge, ok := q.challenges.LoadAndDelete(clientAddress)
+	if err := q.challengeMatchesForClient(clientAddress, challenge); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	expiry := now.Add(1 * time.Minute).UnixNano()
+
+	// The expiry of a client which has already satisfied a challenge is updated in place, to avoid allocating.
+	if v, ok := q.satisfied.Load(clientAddress); ok {
+		v.(*atomic.Int64).Store(expiry)
+	} else {
+		v := &atomic.Int64{}
+		v.Store(expiry)
+		q.satisfied.Store(clientAddress, v)
+	}
+
+	// Satisfied challenges are stored in stateless mode too, so are purged here as well.
+	q.purgeIfDue(now)
+
+	return nil
+}
+
+// ChallengeSatisfied determines whether the client has satisfied a challenge within the last minute.
+func (q *QueryBase) ChallengeSatisfied(clientAddress string) bool {
+	v, ok := q.satisfied.Load(clientAddress)
+	if !ok {
+		return false
+	}
+
+	return time.Now().UnixNano() < v.(*atomic.Int64).Load()
+}
+
+// challengeMatchesForClient determines whether the challenge value supplied by the client is valid.
+func (q *QueryBase) challengeMatchesForClient(clientAddress string, challenge uint32) error {
+	if q.statelessSecret != nil {
+		return q.statelessChallengeMatches(clientAddress, challenge, time.Now().UTC())
+	}
+
+	var (
+		expectedChallenge any
+		ok                bool
+	)
+
+	if q.ReuseChallenges {
+		expectedChallenge, ok = q.challenges.Load(clientAddress)
+	} else {
+		expectedChallenge, ok = q.challenges.LoadAndDelete(clientAddress)
+	}
+
 	if !ok {
 		return ErrNoChallenge
 	}
@@ -75,6 +213,13 @@ func (q *QueryBase) ChallengeMatchesForClient(clientAddress string, challenge ui
 		return ErrChallengeMalformed
 	}
 
+	// Challenges which are only satisfied once are removed before they expire, but reusable challenges must be
+	// expired here, as they may not have been purged yet.
+	if q.ReuseChallenges && time.Now().UTC().After(expectedChallengeEntry.expiryUTC) {
+		q.challenges.Delete(clientAddress)
+		return ErrNoChallenge
+	}
+
 	if challenge != expectedChallengeEntry.value {
 		return ErrChallengeMismatch
 	}
@@ -82,6 +227,21 @@ func (q *QueryBase) ChallengeMatchesForClient(clientAddress string, challenge ui
 	return nil
 }
 
+// purgeIfDue asynchronously purges stale entries if they have not been purged within challengePurgeInterval. Only one
+// purge runs at a time.
+func (q *QueryBase) purgeIfDue(epochUTC time.Time) {
+	if epochUTC.UnixNano() < q.nextPurgeUnixNano.Load() || !q.purging.CompareAndSwap(false, true) {
+		return
+	}
+
+	q.nextPurgeUnixNano.Store(epochUTC.Add(challengePurgeInterval).UnixNano())
+
+	go func() {
+		defer q.purging.Store(false)
+		q.purgeStaleChallenges(epochUTC)
+	}()
+}
+
 // purgeStaleChallenges purges any entries which have an expiry in the past.
 func (q *QueryBase) purgeStaleChallenges(epochUTC time.Time) {
 	q.challenges.Range(func(k any, v any) bool {
@@ -93,4 +253,51 @@ func (q *QueryBase) purgeStaleChallenges(epochUTC time.Time) {
 
 		return true
 	})
+
+	q.satisfied.Range(func(k any, v any) bool {
+		if expiry, ok := v.(*atomic.Int64); ok {
+			if epochUTC.UnixNano() > expiry.Load() {
+				q.satisfied.Delete(k)
+			}
+		}
+
+		return true
+	})
+}
+
+// statelessChallenge derives the challenge for the client address in the interval containing the provided time.
+func (q *QueryBase) statelessChallenge(clientAddress string, epochUTC time.Time) uint32 {
+	m := q.statelessMACs.Get().(*statelessMAC)
+	defer q.statelessMACs.Put(m)
+
+	m.input = binary.BigEndian.AppendUint64(m.input[:0], uint64(epochUTC.UnixNano()/int64(statelessChallengeInterval)))
+	m.input = append(m.input, clientAddress...)
+
+	m.mac.Reset()
+	m.mac.Write(m.input)
+	m.sum = m.mac.Sum(m.sum[:0])
+	v := binary.BigEndian.Uint32(m.sum)
+
+	// Some protocols treat these values as a request for a new challenge, so avoid generating them.
+	if v == 0 || v == 0xFFFFFFFF {
+		v ^= 1
+	}
+
+	return v
+}
+
+// statelessChallengeMatches determines whether the challenge matches one generated for the client address in the
+// current or previous interval. The previous interval is only checked if the current one does not match.
+func (q *QueryBase) statelessChallengeMatches(clientAddress string, challenge uint32, epochUTC time.Time) error {
+	current := q.statelessChallenge(clientAddress, epochUTC)
+	if subtle.ConstantTimeEq(int32(challenge), int32(current)) == 1 {
+		return nil
+	}
+
+	previous := q.statelessChallenge(clientAddress, epochUTC.Add(-statelessChallengeInterval))
+	if subtle.ConstantTimeEq(int32(challenge), int32(previous)) == 1 {
+		return nil
+	}
+
+	return ErrChallengeMismatch
 }
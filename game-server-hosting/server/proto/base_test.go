@@ -36,6 +36,43 @@ func Test_GenerateAndMatchChallenge(t *testing.T) {
 	}, 5*time.Second, 100*time.Millisecond)
 }
 
+func Test_ReuseChallenges(t *testing.T) {
+	t.Parallel()
+
+	q := &QueryBase{ReuseChallenges: true}
+
+	const clientAddr = "client-addr:1234"
+	c, err := q.GenerateChallenge(clientAddr)
+	require.NoError(t, err)
+
+	// The challenge may be satisfied more than once.
+	require.NoError(t, q.ChallengeMatchesForClient(clientAddr, c))
+	require.NoError(t, q.ChallengeMatchesForClient(clientAddr, c))
+	require.ErrorIs(t, q.ChallengeMatchesForClient(clientAddr, c+1), ErrChallengeMismatch)
+
+	// Until it expires.
+	q.challenges.Store(clientAddr, challengeEntry{value: c, expiryUTC: time.Now().UTC().Add(-1 * time.Second)})
+	require.ErrorIs(t, q.ChallengeMatchesForClient(clientAddr, c), ErrNoChallenge)
+	require.ErrorIs(t, q.ChallengeMatchesForClient(clientAddr, c), ErrNoChallenge)
+}
+
+func Test_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	state := &QueryState{ServerName: "foo"}
+	q := &QueryBase{State: state}
+	require.Same(t, state, q.Snapshot())
+
+	other := &QueryState{ServerName: "bar"}
+	q.SetState(other)
+	require.Same(t, other, q.Snapshot())
+
+	// Snapshots take precedence over State.
+	snapshot := &QueryState{ServerName: "baz"}
+	q.Snapshots = NewQueryStatePointer(snapshot)
+	require.Same(t, snapshot, q.Snapshot())
+}
+
 func Test_purgeStaleChallenges(t *testing.T) {
 	t.Parallel()
 
@@ -55,3 +92,106 @@ func Test_purgeStaleChallenges(t *testing.T) {
 
 	require.Equal(t, []string{"127.0.0.2"}, keys)
 }
+
+func Test_StatelessChallenges(t *testing.T) {
+	t.Parallel()
+
+	q := &QueryBase{}
+	require.NoError(t, q.EnableStatelessChallenges())
+
+	const clientAddr = "client-addr:1234"
+	c, err := q.GenerateChallenge(clientAddr)
+	require.NoError(t, err)
+
+	// Nothing is stored per client, so nothing is purged.
+	require.False(t, q.purging.Load())
+	require.Zero(t, q.nextPurgeUnixNano.Load())
+	q.challenges.Range(func(_ any, _ any) bool {
+		t.Fatal("challenge stored in stateless mode")
+		return false
+	})
+
+	// The challenge is only valid for the client it was generated for, and may be reused.
+	require.NoError(t, q.ChallengeMatchesForClient(clientAddr, c))
+	require.NoError(t, q.ChallengeMatchesForClient(clientAddr, c))
+	require.ErrorIs(t, q.ChallengeMatchesForClient("other-addr:1234", c), ErrChallengeMismatch)
+	require.ErrorIs(t, q.ChallengeMatchesForClient(clientAddr, c+1), ErrChallengeMismatch)
+
+	// The challenge is accepted in the following interval, but not after that.
+	now := time.Now().UTC()
+	c = q.statelessChallenge(clientAddr, now)
+	require.NoError(t, q.statelessChallengeMatches(clientAddr, c, now.Add(statelessChallengeInterval)))
+	require.ErrorIs(t, q.statelessChallengeMatches(clientAddr, c, now.Add(2*statelessChallengeInterval)), ErrChallengeMismatch)
+
+	// Challenges differ between responders.
+	other := &QueryBase{}
+	require.NoError(t, other.EnableStatelessChallenges())
+	require.NotEqual(t, c, other.statelessChallenge(clientAddr, now))
+}
+
+func Test_purgeIfDue(t *testing.T) {
+	t.Parallel()
+
+	q := &QueryBase{}
+	now := time.Now().UTC()
+	q.challenges.Store("127.0.0.1", challengeEntry{expiryUTC: now.Add(-1 * time.Minute)})
+
+	q.purgeIfDue(now)
+	require.Eventually(t, func() bool {
+		_, ok := q.challenges.Load("127.0.0.1")
+		return !ok && !q.purging.Load()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Nothing is purged again until the interval has passed.
+	q.challenges.Store("127.0.0.1", challengeEntry{expiryUTC: now.Add(-1 * time.Minute)})
+	q.purgeIfDue(now.Add(challengePurgeInterval / 2))
+	require.False(t, q.purging.Load())
+	_, ok := q.challenges.Load("127.0.0.1")
+	require.True(t, ok)
+
+	// Only one purge runs at a time.
+	q.purging.Store(true)
+	q.purgeIfDue(now.Add(2 * challengePurgeInterval))
+	_, ok = q.challenges.Load("127.0.0.1")
+	require.True(t, ok)
+	require.Equal(t, now.Add(challengePurgeInterval).UnixNano(), q.nextPurgeUnixNano.Load())
+}
+
+func Test_ChallengeSatisfied(t *testing.T) {
+	t.Parallel()
+
+	q := &QueryBase{}
+
+	const clientAddr = "client-addr:1234"
+	require.False(t, q.ChallengeSatisfied(clientAddr))
+
+	c, err := q.GenerateChallenge(clientAddr)
+	require.NoError(t, err)
+	require.False(t, q.ChallengeSatisfied(clientAddr))
+
+	require.NoError(t, q.ChallengeMatchesForClient(clientAddr, c))
+	require.True(t, q.ChallengeSatisfied(clientAddr))
+	require.False(t, q.ChallengeSatisfied("other-addr:1234"))
+
+	// Satisfied challenges are purged once they expire.
+	q.purgeStaleChallenges(time.Now().UTC().Add(2 * time.Minute))
+	require.False(t, q.ChallengeSatisfied(clientAddr))
+}
+
+func Benchmark_StatelessChallengeMatchesForClient(b *testing.B) {
+	q := &QueryBase{}
+	require.NoError(b, q.EnableStatelessChallenges())
+
+	const clientAddr = "client-addr:1234"
+	c, err := q.GenerateChallenge(clientAddr)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err = q.ChallengeMatchesForClient(clientAddr, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
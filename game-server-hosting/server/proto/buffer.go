@@ -0,0 +1,39 @@
+package proto
+
+import (
+	"sync"
+)
+
+const (
+	// defaultBufferSizeBytes is the initial capacity of buffers in the shared buffer pool.
+	defaultBufferSizeBytes = 1024
+
+	// maxPooledBufferSizeBytes is the maximum capacity of a buffer which is returned to the shared buffer pool, so
+	// that an occasional large response does not permanently increase memory usage.
+	maxPooledBufferSizeBytes = 64 * 1024
+)
+
+// bufferPool is a pool of buffers which can be used to build query responses.
+var bufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, defaultBufferSizeBytes)
+		return &b
+	},
+}
+
+// GetBuffer returns an empty buffer from a shared pool. The buffer should be returned with PutBuffer once it is
+// no longer in use.
+func GetBuffer() *[]byte {
+	b, _ := bufferPool.Get().(*[]byte)
+	*b = (*b)[:0]
+	return b
+}
+
+// PutBuffer returns a buffer to the shared pool. The buffer must not be used after it has been returned.
+func PutBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBufferSizeBytes {
+		return
+	}
+
+	bufferPool.Put(b)
+}
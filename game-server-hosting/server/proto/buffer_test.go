@@ -0,0 +1,22 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetPutBuffer(t *testing.T) {
+	t.Parallel()
+
+	b := GetBuffer()
+	require.NotNil(t, b)
+	require.Empty(t, *b)
+	require.GreaterOrEqual(t, cap(*b), defaultBufferSizeBytes)
+
+	*b = append(*b, 1, 2, 3)
+	PutBuffer(b)
+
+	// Buffers are always empty when taken from the pool.
+	require.Empty(t, *GetBuffer())
+}
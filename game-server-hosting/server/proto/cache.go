@@ -0,0 +1,47 @@
+package proto
+
+import (
+	"sync"
+)
+
+// ResponseCache caches encoded query responses for a single query state snapshot, so that responses which do not
+// change between requests are only encoded once. Responses are cached by the identity of the snapshot, rather than
+// its revision, so replacing the snapshot always discards them. Cached responses are discarded once a response for
+// another snapshot is stored. The zero value is ready for use.
+type ResponseCache struct {
+	mtx      sync.RWMutex
+	snapshot *QueryState
+	entries  map[uint32][]byte
+}
+
+// Get returns the response cached for key at the provided snapshot, if any. The returned response must not be
+// modified.
+func (c *ResponseCache) Get(snapshot *QueryState, key uint32) ([]byte, bool) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if c.snapshot != snapshot {
+		return nil, false
+	}
+
+	resp, ok := c.entries[key]
+	return resp, ok
+}
+
+// Put caches a copy of resp as the response for key at the provided snapshot. Responses for a snapshot with an older
+// revision than the one currently cached are ignored, as they have been replaced.
+func (c *ResponseCache) Put(snapshot *QueryState, key uint32, resp []byte) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.snapshot != nil && snapshot.Revision < c.snapshot.Revision {
+		return
+	}
+
+	if c.snapshot != snapshot {
+		c.snapshot = snapshot
+		c.entries = make(map[uint32][]byte)
+	}
+
+	c.entries[key] = append([]byte(nil), resp...)
+}
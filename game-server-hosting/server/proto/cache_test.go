@@ -0,0 +1,56 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ResponseCache(t *testing.T) {
+	t.Parallel()
+
+	first := &QueryState{}
+	second := &QueryState{Revision: 1}
+
+	var c ResponseCache
+	_, ok := c.Get(first, 1)
+	require.False(t, ok)
+
+	resp := []byte{1, 2, 3}
+	c.Put(first, 1, resp)
+	resp[0] = 4
+
+	// A copy of the response is cached.
+	cached, ok := c.Get(first, 1)
+	require.True(t, ok)
+	require.Equal(t, []byte{1, 2, 3}, cached)
+
+	_, ok = c.Get(first, 2)
+	require.False(t, ok)
+
+	// Responses for a newer snapshot replace all cached responses.
+	c.Put(second, 2, []byte{5})
+	_, ok = c.Get(first, 1)
+	require.False(t, ok)
+	_, ok = c.Get(second, 1)
+	require.False(t, ok)
+
+	cached, ok = c.Get(second, 2)
+	require.True(t, ok)
+	require.Equal(t, []byte{5}, cached)
+
+	// Responses for an older revision are ignored.
+	c.Put(first, 1, []byte{6})
+	_, ok = c.Get(first, 1)
+	require.False(t, ok)
+
+	// A snapshot replaced without incrementing its revision is not served from the cache.
+	third := &QueryState{Revision: 1}
+	_, ok = c.Get(third, 2)
+	require.False(t, ok)
+
+	c.Put(third, 2, []byte{7})
+	cached, ok = c.Get(third, 2)
+	require.True(t, ok)
+	require.Equal(t, []byte{7}, cached)
+}
@@ -0,0 +1,33 @@
+package gs4
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// UnsupportedQueryError is an error which represents an unsupported GameSpy4 packet type.
+	UnsupportedQueryError struct {
+		packetType byte
+	}
+)
+
+var (
+	// errInvalidPacketLength defines an error in which the input is too short for the request type.
+	errInvalidPacketLength = errors.New("invalid packet length")
+
+	// errInvalidMagic defines an error in which the input does not begin with the GameSpy4 magic bytes.
+	errInvalidMagic = errors.New("invalid magic")
+)
+
+// NewUnsupportedQueryError returns a new instance of UnsupportedQueryError.
+func NewUnsupportedQueryError(packetType byte) error {
+	return &UnsupportedQueryError{
+		packetType: packetType,
+	}
+}
+
+// Error returns the error string.
+func (e *UnsupportedQueryError) Error() string {
+	return fmt.Sprintf("unsupported query: %x", e.packetType)
+}
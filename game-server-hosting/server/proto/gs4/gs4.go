@@ -0,0 +1,303 @@
+package gs4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+type (
+	// QueryResponder implements proto.QueryResponder for the GameSpy4 query protocol, as used by Unreal
+	// Tournament 3 and the Minecraft UDP query.
+	QueryResponder struct {
+		*proto.QueryBase
+		enc *encoder
+	}
+
+	// responseHeaderWireFormat describes the header common to all GameSpy4 responses.
+	responseHeaderWireFormat struct {
+		Type      byte
+		SessionID uint32
+	}
+
+	// handshakeWireFormat describes the format of a handshake response.
+	handshakeWireFormat struct {
+		Header    responseHeaderWireFormat
+		Challenge string
+	}
+
+	// basicStatWireFormat describes the format of a basic stat response.
+	basicStatWireFormat struct {
+		Header     responseHeaderWireFormat
+		ServerName string
+		GameType   string
+		GameMap    string
+		NumPlayers string
+		MaxPlayers string
+	}
+
+	// request represents a parsed GameSpy4 request.
+	request struct {
+		Type      byte
+		SessionID uint32
+		Challenge uint32
+		FullStat  bool
+	}
+)
+
+const (
+	// packetTypeHandshake requests a challenge token.
+	packetTypeHandshake = byte(0x09)
+
+	// packetTypeStat requests either the basic or full stat, depending on the length of the request.
+	packetTypeStat = byte(0x00)
+
+	// sessionIDMask masks the session ID to the bits which are significant to clients.
+	sessionIDMask = 0x0F0F0F0F
+)
+
+var (
+	magic = []byte{0xFE, 0xFD}
+
+	// fullStatPadding precedes the key/value section of a full stat response.
+	fullStatPadding = []byte("splitnum\x00\x80\x00")
+
+	// playerSectionPadding precedes the player section of a full stat response.
+	playerSectionPadding = []byte("\x01player_\x00\x00")
+)
+
+// NewQueryResponder returns creates a new responder capable of responding
+// to GameSpy4-formatted queries, using the provided query state. The state is read for each query, so it may be
+// modified between queries.
+func NewQueryResponder(state *proto.QueryState) (*QueryResponder, error) {
+	return newQueryResponder(&proto.QueryBase{State: state}), nil
+}
+
+// NewQueryResponderFromSnapshot creates a new responder capable of responding to GameSpy4-formatted queries,
+// using the query state snapshot currently held by the provided pointer. Snapshots must not be modified.
+func NewQueryResponderFromSnapshot(state *atomic.Pointer[proto.QueryState]) (*QueryResponder, error) {
+	return newQueryResponder(&proto.QueryBase{Snapshots: state}), nil
+}
+
+// newQueryResponder creates a new responder which reads the query state from the provided base.
+func newQueryResponder(base *proto.QueryBase) *QueryResponder {
+	// Clients reuse a challenge token for both basic and full stat requests until it expires.
+	base.ReuseChallenges = true
+
+	return &QueryResponder{
+		QueryBase: base,
+		enc:       &encoder{},
+	}
+}
+
+// Respond writes a query response to the requester in the GameSpy4 wire protocol.
+func (q *QueryResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	req, err := parseRequest(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case packetTypeHandshake:
+		return q.handleHandshake(clientAddress, req)
+
+	case packetTypeStat:
+		if err = q.ChallengeMatchesForClient(clientAddress, req.Challenge); err != nil {
+			return nil, err
+		}
+
+		if req.FullStat {
+			return q.handleFullStat(req)
+		}
+
+		return q.handleBasicStat(req)
+	}
+
+	return nil, NewUnsupportedQueryError(req.Type)
+}
+
+// handleHandshake handles an incoming handshake request, responding with a challenge token.
+func (q *QueryResponder) handleHandshake(clientAddress string, req *request) ([]byte, error) {
+	challenge, err := q.GenerateChallenge(clientAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := bytes.NewBuffer(nil)
+	if err = proto.WireWrite(resp, q.enc, handshakeWireFormat{
+		Header: responseHeaderWireFormat{
+			Type:      packetTypeHandshake,
+			SessionID: req.SessionID,
+		},
+		// Clients parse the token as a signed 32-bit integer.
+		Challenge: strconv.FormatInt(int64(int32(challenge)), 10),
+	}); err != nil {
+		return nil, err
+	}
+
+	return resp.Bytes(), nil
+}
+
+// handleBasicStat handles an incoming basic stat request.
+func (q *QueryResponder) handleBasicStat(req *request) ([]byte, error) {
+	w := basicStatWireFormat{
+		Header: responseHeaderWireFormat{
+			Type:      packetTypeStat,
+			SessionID: req.SessionID,
+		},
+		ServerName: "n/a",
+		GameType:   "n/a",
+		GameMap:    "n/a",
+		NumPlayers: "0",
+		MaxPlayers: "0",
+	}
+
+	var port uint16
+	if qs := q.Snapshot(); qs != nil {
+		w.ServerName = qs.ServerName
+		w.GameType = qs.GameType
+		w.GameMap = qs.Map
+		w.NumPlayers = strconv.Itoa(int(qs.CurrentPlayers))
+		w.MaxPlayers = strconv.Itoa(int(qs.MaxPlayers))
+		port = qs.Port
+	}
+
+	resp := bytes.NewBuffer(nil)
+	if err := proto.WireWrite(resp, q.enc, w); err != nil {
+		return nil, err
+	}
+
+	// The host port is the only little-endian value in the protocol. The host IP is not known to the query state,
+	// so it is left empty.
+	if err := binary.Write(resp, binary.LittleEndian, port); err != nil {
+		return nil, err
+	}
+
+	if err := q.enc.WriteString(resp, ""); err != nil {
+		return nil, err
+	}
+
+	return resp.Bytes(), nil
+}
+
+// handleFullStat handles an incoming full stat request.
+func (q *QueryResponder) handleFullStat(req *request) ([]byte, error) {
+	resp := bytes.NewBuffer(nil)
+	if err := proto.WireWrite(resp, q.enc, responseHeaderWireFormat{
+		Type:      packetTypeStat,
+		SessionID: req.SessionID,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := q.enc.Write(resp, fullStatPadding); err != nil {
+		return nil, err
+	}
+
+	// Key/value section, terminated by an empty key.
+	for _, kv := range q.serverInfo() {
+		if err := q.enc.WriteString(resp, kv[0]); err != nil {
+			return nil, err
+		}
+
+		if err := q.enc.WriteString(resp, kv[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := q.enc.WriteString(resp, ""); err != nil {
+		return nil, err
+	}
+
+	// Player section, terminated by an empty name.
+	if err := q.enc.Write(resp, playerSectionPadding); err != nil {
+		return nil, err
+	}
+
+	if qs := q.Snapshot(); qs != nil {
+		for _, p := range qs.Players {
+			if err := q.enc.WriteString(resp, p.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := q.enc.WriteString(resp, ""); err != nil {
+		return nil, err
+	}
+
+	return resp.Bytes(), nil
+}
+
+// serverInfo returns the key/value pairs reported in the full stat response. Any server rules follow the
+// standard keys, in order of their key.
+func (q *QueryResponder) serverInfo() [][2]string {
+	qs := q.Snapshot()
+	if qs == nil {
+		return [][2]string{
+			{"hostname", "n/a"},
+			{"gametype", "n/a"},
+			{"map", "n/a"},
+			{"numplayers", "0"},
+			{"maxplayers", "0"},
+		}
+	}
+
+	info := [][2]string{
+		{"hostname", qs.ServerName},
+		{"gametype", qs.GameType},
+		{"version", qs.Version},
+		{"map", qs.Map},
+		{"numplayers", strconv.Itoa(int(qs.CurrentPlayers))},
+		{"maxplayers", strconv.Itoa(int(qs.MaxPlayers))},
+		{"hostport", strconv.Itoa(int(qs.Port))},
+	}
+
+	keys := make([]string, 0, len(qs.Rules))
+	for k := range qs.Rules {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		info = append(info, [2]string{k, fmt.Sprint(qs.Rules[k])})
+	}
+
+	return info
+}
+
+// parseRequest parses the incoming request as a GameSpy4 request. Stat requests which include padding after the
+// challenge are treated as full stat requests.
+func parseRequest(buf []byte) (*request, error) {
+	if len(buf) < 7 {
+		return nil, errInvalidPacketLength
+	}
+
+	if !bytes.Equal(buf[0:2], magic) {
+		return nil, errInvalidMagic
+	}
+
+	req := &request{
+		Type:      buf[2],
+		SessionID: binary.BigEndian.Uint32(buf[3:7]) & sessionIDMask,
+	}
+
+	if req.Type != packetTypeStat {
+		return req, nil
+	}
+
+	if len(buf) < 11 {
+		return nil, errInvalidPacketLength
+	}
+
+	req.Challenge = binary.BigEndian.Uint32(buf[7:11])
+	req.FullStat = len(buf) >= 15
+
+	return req, nil
+}
@@ -0,0 +1,146 @@
+package gs4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"testing"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/stretchr/testify/require"
+)
+
+const addrKey = "client-addr:65534"
+
+// handshake performs a handshake with the responder, returning the challenge token in its wire format.
+func handshake(t *testing.T, q *QueryResponder) []byte {
+	t.Helper()
+
+	resp, err := q.Respond(addrKey, []byte{0xFE, 0xFD, 0x09, 0x00, 0x00, 0x00, 0x01})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x09, 0x00, 0x00, 0x00, 0x01}, resp[0:5])
+	require.Equal(t, byte(0), resp[len(resp)-1])
+
+	token, err := strconv.ParseInt(string(resp[5:len(resp)-1]), 10, 32)
+	require.NoError(t, err)
+
+	challenge := make([]byte, 4)
+	binary.BigEndian.PutUint32(challenge, uint32(int32(token)))
+	return challenge
+}
+
+func Test_Respond_BasicStat(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     2,
+		ServerName:     "foo",
+		GameType:       "SMP",
+		Map:            "world",
+		Port:           25565,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	challenge := handshake(t, q)
+
+	resp, err := q.Respond(addrKey, bytes.Join([][]byte{{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01}, challenge}, nil))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{0x00, 0x00, 0x00, 0x00, 0x01},
+				[]byte("foo\x00SMP\x00world\x001\x002\x00"),
+				{0xDD, 0x63},
+				{0x00},
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_FullStat(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 2,
+		MaxPlayers:     4,
+		ServerName:     "foo",
+		GameType:       "SMP",
+		Map:            "world",
+		Version:        "1.20",
+		Port:           25565,
+		Rules:          map[string]any{"plugins": ""},
+		Players: []proto.QueryPlayer{
+			{Name: "one"},
+			{Name: "two"},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	challenge := handshake(t, q)
+
+	resp, err := q.Respond(
+		addrKey,
+		bytes.Join([][]byte{{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01}, challenge, {0x00, 0x00, 0x00, 0x00}}, nil),
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{0x00, 0x00, 0x00, 0x00, 0x01},
+				fullStatPadding,
+				[]byte("hostname\x00foo\x00gametype\x00SMP\x00version\x001.20\x00map\x00world\x00"),
+				[]byte("numplayers\x002\x00maxplayers\x004\x00hostport\x0025565\x00plugins\x00\x00"),
+				{0x00},
+				playerSectionPadding,
+				[]byte("one\x00two\x00"),
+				{0x00},
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_reuseChallenge(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{ServerName: "foo"})
+	require.NoError(t, err)
+
+	challenge := handshake(t, q)
+
+	// The same token is accepted for a basic stat request followed by a full stat request.
+	resp, err := q.Respond(addrKey, bytes.Join([][]byte{{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01}, challenge}, nil))
+	require.NoError(t, err)
+	require.Contains(t, string(resp), "foo")
+
+	resp, err = q.Respond(
+		addrKey,
+		bytes.Join([][]byte{{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01}, challenge, {0x00, 0x00, 0x00, 0x00}}, nil),
+	)
+	require.NoError(t, err)
+	require.Contains(t, string(resp), "hostname\x00foo")
+}
+
+func Test_Respond_errors(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{})
+	require.NoError(t, err)
+
+	_, err = q.Respond(addrKey, []byte{0xFE, 0xFD, 0x09})
+	require.ErrorIs(t, err, errInvalidPacketLength)
+
+	_, err = q.Respond(addrKey, []byte{0xFF, 0xFF, 0x09, 0x00, 0x00, 0x00, 0x01})
+	require.ErrorIs(t, err, errInvalidMagic)
+
+	_, err = q.Respond(addrKey, []byte{0xFE, 0xFD, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01})
+	require.ErrorIs(t, err, proto.ErrNoChallenge)
+
+	var unsupported *UnsupportedQueryError
+	_, err = q.Respond(addrKey, []byte{0xFE, 0xFD, 0x01, 0x00, 0x00, 0x00, 0x01})
+	require.ErrorAs(t, err, &unsupported)
+}
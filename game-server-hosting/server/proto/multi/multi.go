@@ -0,0 +1,141 @@
+package multi
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/a2s"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
+)
+
+type (
+	// QueryResponder implements proto.QueryResponder, dispatching each request to either an SQP or A2S responder.
+	QueryResponder struct {
+		sqp *sqp.QueryResponder
+		a2s *a2s.QueryResponder
+	}
+)
+
+// errUnsupportedQuery defines an error in which the protocol of the request could not be detected.
+var errUnsupportedQuery = errors.New("unsupported query")
+
+// a2sHeader is the header which all single-packet A2S requests begin with.
+var a2sHeader = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+// NewQueryResponder returns creates a new responder capable of responding
+// to both SQP and A2S-formatted queries, using the provided query state. The state is read for each query, so
+// responses are not cached.
+func NewQueryResponder(state *proto.QueryState) (*QueryResponder, error) {
+	s, err := sqp.NewQueryResponder(state)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := a2s.NewQueryResponder(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryResponder{
+		sqp: s,
+		a2s: a,
+	}, nil
+}
+
+// NewQueryResponderFromSnapshot creates a new responder capable of responding to both SQP and A2S-formatted queries,
+// using the query state snapshot currently held by the provided pointer. Snapshots must not be modified, as
+// responses are cached until the snapshot is replaced.
+func NewQueryResponderFromSnapshot(state *atomic.Pointer[proto.QueryState]) (*QueryResponder, error) {
+	s, err := sqp.NewQueryResponderFromSnapshot(state)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := a2s.NewQueryResponderFromSnapshot(state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryResponder{
+		sqp: s,
+		a2s: a,
+	}, nil
+}
+
+// SetState replaces the query state used to respond to queries by a responder created with NewQueryResponder. It
+// must not be called while a query is being answered.
+func (q *QueryResponder) SetState(state *proto.QueryState) {
+	q.sqp.SetState(state)
+	q.a2s.SetState(state)
+}
+
+// Respond writes a query response to the requester in the wire protocol of the request.
+func (q *QueryResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	return q.AppendResponse(nil, clientAddress, buf)
+}
+
+// AppendResponse appends a query response in the wire protocol of the request to dst.
+func (q *QueryResponder) AppendResponse(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
+	switch {
+	case isA2S(buf):
+		return q.a2s.AppendResponse(dst, clientAddress, buf)
+
+	case isSQP(buf):
+		return q.sqp.AppendResponse(dst, clientAddress, buf)
+	}
+
+	return nil, errUnsupportedQuery
+}
+
+// RespondPackets writes a query response to the requester in the wire protocol of the request. A2S responses which
+// exceed maxPacketSize are split across multiple packets.
+func (q *QueryResponder) RespondPackets(clientAddress string, buf []byte, maxPacketSize int) ([][]byte, error) {
+	if isA2S(buf) {
+		return q.a2s.RespondPackets(clientAddress, buf, maxPacketSize)
+	}
+
+	resp, err := q.Respond(clientAddress, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{resp}, nil
+}
+
+// SplitResponse splits an A2S response across multiple packets if it exceeds maxPacketSize bytes. Other responses
+// are returned as-is.
+func (q *QueryResponder) SplitResponse(resp []byte, maxPacketSize int) ([][]byte, error) {
+	if isA2S(resp) {
+		return q.a2s.SplitResponse(resp, maxPacketSize)
+	}
+
+	return [][]byte{resp}, nil
+}
+
+// EnableStatelessChallenges switches challenge generation of both the SQP and A2S responders to a stateless mode.
+// See proto.QueryBase for details.
+func (q *QueryResponder) EnableStatelessChallenges() error {
+	if err := q.sqp.EnableStatelessChallenges(); err != nil {
+		return err
+	}
+
+	return q.a2s.EnableStatelessChallenges()
+}
+
+// ChallengeSatisfied determines whether the client has recently satisfied a challenge of either the SQP or A2S
+// responder.
+func (q *QueryResponder) ChallengeSatisfied(clientAddress string) bool {
+	return q.sqp.ChallengeSatisfied(clientAddress) || q.a2s.ChallengeSatisfied(clientAddress)
+}
+
+// isA2S determines if the input buffer corresponds to an A2S request.
+func isA2S(buf []byte) bool {
+	return len(buf) >= 5 && bytes.Equal(buf[0:4], a2sHeader)
+}
+
+// isSQP determines if the input buffer corresponds to an SQP challenge or query request.
+func isSQP(buf []byte) bool {
+	return len(buf) >= 5 && (buf[0] == 0 || buf[0] == 1)
+}
@@ -0,0 +1,69 @@
+package multi
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Respond(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		ServerName: "foo",
+		Rules:      map[string]any{"mode": "ctf"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	const addr = "client-addr:65534"
+
+	// SQP challenge
+	resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	require.Len(t, resp, 5)
+	require.Equal(t, byte(0), resp[0])
+
+	// SQP query
+	resp, err = q.Respond(addr, bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0b00000001}}, nil))
+	require.NoError(t, err)
+	require.Equal(t, byte(1), resp[0])
+
+	// A2S challenge
+	packets, err := q.RespondPackets(addr, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x56, 0xFF, 0xFF, 0xFF, 0xFF}, 1024)
+	require.NoError(t, err)
+	require.Len(t, packets, 1)
+	require.Equal(t, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x41}, packets[0][0:5])
+
+	// A2S rules
+	packets, err = q.RespondPackets(addr, bytes.Join([][]byte{{0xFF, 0xFF, 0xFF, 0xFF, 0x56}, packets[0][5:9]}, nil), 1024)
+	require.NoError(t, err)
+	require.Len(t, packets, 1)
+	require.Equal(t, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x45}, packets[0][0:5])
+
+	// Unknown
+	_, err = q.Respond(addr, []byte{2, 0, 0, 0, 0})
+	require.ErrorIs(t, err, errUnsupportedQuery)
+	_, err = q.RespondPackets(addr, []byte{0xFF}, 1024)
+	require.ErrorIs(t, err, errUnsupportedQuery)
+}
+
+func Test_EnableStatelessChallenges(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{})
+	require.NoError(t, err)
+	require.NoError(t, q.EnableStatelessChallenges())
+
+	const addr = "client-addr:65534"
+
+	// The same challenge is valid for repeated SQP queries.
+	resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+
+	query := bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0b00000001}}, nil)
+	_, err = q.Respond(addr, query)
+	require.NoError(t, err)
+	_, err = q.Respond(addr, query)
+	require.NoError(t, err)
+}
@@ -0,0 +1,23 @@
+package q3
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+type (
+	// encoder is a struct which implements proto.WireEncoder.
+	encoder struct{}
+)
+
+// WriteString writes a string to the provided buffer. Strings are written as-is, as the Quake 3 protocol
+// is text-based and delimits values itself.
+func (e *encoder) WriteString(resp *bytes.Buffer, s string) error {
+	_, err := resp.WriteString(s)
+	return err
+}
+
+// Write writes arbitrary data to the provided buffer.
+func (e *encoder) Write(resp *bytes.Buffer, v interface{}) error {
+	return binary.Write(resp, binary.LittleEndian, v)
+}
@@ -0,0 +1,28 @@
+package q3
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// UnsupportedQueryError is an error which represents an unsupported Quake 3 connectionless command.
+	UnsupportedQueryError struct {
+		command string
+	}
+)
+
+// errNotConnectionless defines an error in which the input is not a connectionless packet.
+var errNotConnectionless = errors.New("not a connectionless packet")
+
+// NewUnsupportedQueryError returns a new instance of UnsupportedQueryError.
+func NewUnsupportedQueryError(command string) error {
+	return &UnsupportedQueryError{
+		command: command,
+	}
+}
+
+// Error returns the error string.
+func (e *UnsupportedQueryError) Error() string {
+	return fmt.Sprintf("unsupported query: %q", e.command)
+}
@@ -0,0 +1,276 @@
+package q3
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+type (
+	// QueryResponder implements proto.QueryResponder for the Quake 3 (ioquake3) connectionless query protocol.
+	QueryResponder struct {
+		*proto.QueryBase
+		enc *encoder
+	}
+
+	// infoKeyValue represents a single key/value pair in an infostring.
+	infoKeyValue struct {
+		Key   string
+		Value string
+	}
+
+	// request represents a parsed connectionless request.
+	request struct {
+		Command   string
+		Challenge string
+	}
+)
+
+const (
+	// commandGetStatus requests the server info and the list of players.
+	commandGetStatus = "getstatus"
+
+	// commandGetInfo requests a summary of the server info.
+	commandGetInfo = "getinfo"
+
+	// protocolVersion is the network protocol version reported in getinfo responses.
+	protocolVersion = 68
+)
+
+var connectionlessHeader = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+// NewQueryResponder returns creates a new responder capable of responding
+// to Quake 3-formatted queries, using the provided query state. The state is read for each query, so it may be
+// modified between queries.
+func NewQueryResponder(state *proto.QueryState) (*QueryResponder, error) {
+	q := &QueryResponder{
+		QueryBase: &proto.QueryBase{
+			State: state,
+		},
+		enc: &encoder{},
+	}
+
+	return q, nil
+}
+
+// NewQueryResponderFromSnapshot creates a new responder capable of responding to Quake 3-formatted queries,
+// using the query state snapshot currently held by the provided pointer. Snapshots must not be modified.
+func NewQueryResponderFromSnapshot(state *atomic.Pointer[proto.QueryState]) (*QueryResponder, error) {
+	q := &QueryResponder{
+		QueryBase: &proto.QueryBase{
+			Snapshots: state,
+		},
+		enc: &encoder{},
+	}
+
+	return q, nil
+}
+
+// Respond writes a query response to the requester in the Quake 3 wire protocol. The Quake 3 protocol echoes a
+// client-supplied challenge rather than performing a handshake, so no challenge state is held.
+func (q *QueryResponder) Respond(_ string, buf []byte) ([]byte, error) {
+	req, err := parseRequest(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Command {
+	case commandGetStatus:
+		return q.handleStatus(req)
+
+	case commandGetInfo:
+		return q.handleInfo(req)
+	}
+
+	return nil, NewUnsupportedQueryError(req.Command)
+}
+
+// handleStatus handles an incoming getstatus request.
+func (q *QueryResponder) handleStatus(req *request) ([]byte, error) {
+	info := []infoKeyValue{
+		{Key: "sv_hostname", Value: "n/a"},
+		{Key: "mapname", Value: "n/a"},
+	}
+
+	var players []proto.QueryPlayer
+
+	if qs := q.Snapshot(); qs != nil {
+		info = []infoKeyValue{
+			{Key: "sv_hostname", Value: qs.ServerName},
+			{Key: "mapname", Value: qs.Map},
+			{Key: "sv_maxclients", Value: strconv.Itoa(int(qs.MaxPlayers))},
+			{Key: "g_gametype", Value: qs.GameType},
+			{Key: "g_needpass", Value: boolString(qs.PasswordProtected)},
+		}
+
+		if qs.Version != "" {
+			info = append(info, infoKeyValue{Key: "version", Value: qs.Version})
+		}
+
+		info = append(info, rulesToInfo(qs.Rules)...)
+		players = qs.Players
+	}
+
+	if req.Challenge != "" {
+		info = append(info, infoKeyValue{Key: "challenge", Value: req.Challenge})
+	}
+
+	resp := bytes.NewBuffer(nil)
+	if err := q.writeHeader(resp, "statusResponse"); err != nil {
+		return nil, err
+	}
+
+	if err := q.writeInfoString(resp, info); err != nil {
+		return nil, err
+	}
+
+	if err := q.enc.WriteString(resp, "\n"); err != nil {
+		return nil, err
+	}
+
+	for _, p := range players {
+		line := fmt.Sprintf("%d %d \"%s\"\n", p.Score, p.Ping, sanitize(p.Name))
+		if err := q.enc.WriteString(resp, line); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp.Bytes(), nil
+}
+
+// handleInfo handles an incoming getinfo request.
+func (q *QueryResponder) handleInfo(req *request) ([]byte, error) {
+	info := []infoKeyValue{
+		{Key: "protocol", Value: strconv.Itoa(protocolVersion)},
+		{Key: "hostname", Value: "n/a"},
+		{Key: "mapname", Value: "n/a"},
+	}
+
+	if qs := q.Snapshot(); qs != nil {
+		players := qs.CurrentPlayers
+		humans := players - qs.Bots
+		if humans < 0 {
+			humans = 0
+		}
+
+		info = []infoKeyValue{
+			{Key: "protocol", Value: strconv.Itoa(protocolVersion)},
+			{Key: "hostname", Value: qs.ServerName},
+			{Key: "mapname", Value: qs.Map},
+			{Key: "clients", Value: strconv.Itoa(int(players))},
+			{Key: "g_humanplayers", Value: strconv.Itoa(int(humans))},
+			{Key: "sv_maxclients", Value: strconv.Itoa(int(qs.MaxPlayers))},
+			{Key: "gametype", Value: qs.GameType},
+			{Key: "g_needpass", Value: boolString(qs.PasswordProtected)},
+		}
+	}
+
+	if req.Challenge != "" {
+		info = append(info, infoKeyValue{Key: "challenge", Value: req.Challenge})
+	}
+
+	resp := bytes.NewBuffer(nil)
+	if err := q.writeHeader(resp, "infoResponse"); err != nil {
+		return nil, err
+	}
+
+	if err := q.writeInfoString(resp, info); err != nil {
+		return nil, err
+	}
+
+	return resp.Bytes(), nil
+}
+
+// writeHeader writes the connectionless packet header and response command to the provided buffer.
+func (q *QueryResponder) writeHeader(resp *bytes.Buffer, command string) error {
+	if err := q.enc.Write(resp, connectionlessHeader); err != nil {
+		return err
+	}
+
+	return q.enc.WriteString(resp, command+"\n")
+}
+
+// writeInfoString writes the provided key/value pairs to the buffer in the backslash-delimited infostring format.
+func (q *QueryResponder) writeInfoString(resp *bytes.Buffer, info []infoKeyValue) error {
+	for _, kv := range info {
+		if err := q.enc.WriteString(resp, "\\"+sanitize(kv.Key)+"\\"+sanitize(kv.Value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseRequest parses the incoming request as a connectionless command, with an optional challenge argument.
+func parseRequest(buf []byte) (*request, error) {
+	if len(buf) < len(connectionlessHeader) || !bytes.Equal(buf[0:4], connectionlessHeader) {
+		return nil, errNotConnectionless
+	}
+
+	// The request is terminated by a null byte, if present.
+	body := buf[4:]
+	if n := bytes.IndexByte(body, 0); n >= 0 {
+		body = body[:n]
+	}
+
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return nil, NewUnsupportedQueryError("")
+	}
+
+	req := &request{
+		Command: fields[0],
+	}
+
+	if len(fields) > 1 {
+		req.Challenge = fields[1]
+	}
+
+	return req, nil
+}
+
+// rulesToInfo converts the provided rules to infostring key/value pairs, in order of their key.
+func rulesToInfo(rules map[string]any) []infoKeyValue {
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	info := make([]infoKeyValue, 0, len(keys))
+	for _, k := range keys {
+		v := rules[k]
+		if b, ok := v.(bool); ok {
+			v = boolString(b)
+		}
+
+		info = append(info, infoKeyValue{Key: k, Value: fmt.Sprint(v)})
+	}
+
+	return info
+}
+
+// sanitize removes characters which cannot be represented in an infostring or player line.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\\', '"', ';', '\n':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// boolString formats a boolean as "1" or "0".
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,93 @@
+package q3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Respond_GetStatus(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 2,
+		MaxPlayers:     8,
+		ServerName:     "foo\\bar",
+		Map:            "q3dm17",
+		GameType:       "ffa",
+		Rules:          map[string]any{"fraglimit": 20},
+		Players: []proto.QueryPlayer{
+			{Name: "player \"one\"", Score: 10, Ping: 50},
+			{Name: "two", Score: -1, Ping: 0},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	// Request buffers from the query port are padded with null bytes.
+	req := make([]byte, 64)
+	copy(req, "\xFF\xFF\xFF\xFFgetstatus 1234\n")
+
+	resp, err := q.Respond("client-addr:1234", req)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{0xFF, 0xFF, 0xFF, 0xFF},
+				[]byte("statusResponse\n"),
+				[]byte(`\sv_hostname\foobar\mapname\q3dm17\sv_maxclients\8\g_gametype\ffa\g_needpass\0`),
+				[]byte(`\fraglimit\20\challenge\1234` + "\n"),
+				[]byte("10 50 \"player one\"\n"),
+				[]byte("-1 0 \"two\"\n"),
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_GetInfo(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers:    3,
+		MaxPlayers:        8,
+		Bots:              1,
+		ServerName:        "foo",
+		Map:               "q3dm17",
+		GameType:          "ffa",
+		PasswordProtected: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	resp, err := q.Respond("client-addr:1234", []byte("\xFF\xFF\xFF\xFFgetinfo"))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{0xFF, 0xFF, 0xFF, 0xFF},
+				[]byte("infoResponse\n"),
+				[]byte(`\protocol\68\hostname\foo\mapname\q3dm17\clients\3\g_humanplayers\2\sv_maxclients\8`),
+				[]byte(`\gametype\ffa\g_needpass\1`),
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_unsupported(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{})
+	require.NoError(t, err)
+
+	_, err = q.Respond("client-addr:1234", []byte("\xFF\xFF\xFF\xFFgetchallenge"))
+	var unsupported *UnsupportedQueryError
+	require.ErrorAs(t, err, &unsupported)
+
+	_, err = q.Respond("client-addr:1234", []byte{0, 0, 0, 0, 0})
+	require.ErrorIs(t, err, errNotConnectionless)
+}
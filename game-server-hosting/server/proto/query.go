@@ -3,6 +3,7 @@ package proto
 import (
 	"bytes"
 	"reflect"
+	"time"
 )
 
 type (
@@ -12,6 +13,46 @@ type (
 		Respond(clientAddress string, buf []byte) ([]byte, error)
 	}
 
+	// MultiPacketResponder represents an interface to a concrete type which responds to query requests and is able
+	// to split responses which exceed a maximum packet size across multiple packets.
+	MultiPacketResponder interface {
+		QueryResponder
+		RespondPackets(clientAddress string, buf []byte, maxPacketSize int) ([][]byte, error)
+	}
+
+	// StatelessChallengeResponder represents an interface to a concrete type which responds to query requests and
+	// is able to generate challenges without storing any per-client state.
+	StatelessChallengeResponder interface {
+		QueryResponder
+		EnableStatelessChallenges() error
+	}
+
+	// ChallengeVerifier represents an interface to a concrete type which is able to report whether a client has
+	// recently satisfied a challenge, proving it owns its source address.
+	ChallengeVerifier interface {
+		ChallengeSatisfied(clientAddress string) bool
+	}
+
+	// AppendResponder represents an interface to a concrete type which responds to query requests by appending the
+	// response to a provided buffer, allowing buffers to be reused between requests.
+	AppendResponder interface {
+		QueryResponder
+		AppendResponse(dst []byte, clientAddress string, buf []byte) ([]byte, error)
+	}
+
+	// ResponseSplitter represents an interface to a concrete type which is able to split a response it generated
+	// across multiple packets, if it exceeds a maximum packet size. Responses which fit into a single packet are
+	// returned as-is.
+	ResponseSplitter interface {
+		SplitResponse(resp []byte, maxPacketSize int) ([][]byte, error)
+	}
+
+	// WireAppender is an interface which allows a wire format to append its encoding to a byte slice without
+	// the use of reflection.
+	WireAppender interface {
+		AppendTo(dst []byte) []byte
+	}
+
 	// WireEncoder is an interface which allows for different query implementations
 	// to write data to a byte buffer in a specific format.
 	WireEncoder interface {
@@ -28,6 +69,73 @@ type (
 		Map            string
 		Port           uint16
 		Metrics        []float32
+		Players        []QueryPlayer
+		Teams          []QueryTeam
+
+		// Version is the version string of the game.
+		Version string
+
+		// Bots is the number of bots currently in the game.
+		Bots int32
+
+		// PasswordProtected denotes whether a password is required to join the game.
+		PasswordProtected bool
+
+		// Keywords holds any tags which describe the game, for use in server filters.
+		Keywords []string
+
+		// SteamID is the Steam ID of the server, if it has one.
+		SteamID uint64
+
+		// SourceTVPort and SourceTVName describe the spectator (SourceTV) endpoint of the server, if it has one.
+		SourceTVPort uint16
+		SourceTVName string
+
+		// GameID is the 64-bit game ID of the server, if it has one. The lower 24 bits hold the Steam App ID.
+		GameID uint64
+
+		// Rules holds any named server rules, for example the game mode or current round. Support for value types
+		// varies between query protocols.
+		Rules map[string]any
+
+		// Revision is incremented each time the state is changed by the server, allowing the order of snapshots to
+		// be determined.
+		Revision uint64
+	}
+
+	// QueryPlayer represents a single player in a currently running game.
+	QueryPlayer struct {
+		// ID uniquely identifies the player within the game. It is not reported in query responses.
+		ID string
+
+		// Name is the display name of the player.
+		Name string
+
+		// Score is the current score of the player.
+		Score int32
+
+		// Ping is the latency of the player in milliseconds.
+		Ping uint16
+
+		// JoinedAt is the time at which the player joined the game.
+		JoinedAt time.Time
+
+		// Fields holds any additional values to report for the player, keyed by field name. Support for value types
+		// varies between query protocols.
+		Fields map[string]any
+	}
+
+	// QueryTeam represents a single team in a currently running game.
+	QueryTeam struct {
+		// Name is the name of the team, which uniquely identifies it within the game.
+		Name string
+
+		// Score is the current score of the team.
+		Score int32
+
+		// Fields holds any additional values to report for the team, keyed by field name. Support for value types
+		// varies between query protocols.
+		Fields map[string]any
 	}
 )
 
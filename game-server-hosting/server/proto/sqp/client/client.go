@@ -0,0 +1,290 @@
+// Package client implements a client for the SQP query protocol, which performs the challenge handshake and decodes
+// query responses into Go types.
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+type (
+	// Client represents a client which queries a single server using the SQP protocol. It is safe for concurrent
+	// use, although queries are performed one at a time.
+	Client struct {
+		conn    net.Conn
+		timeout time.Duration
+		version uint16
+
+		// mtx ensures only one query is in flight at a time, so that responses cannot be mismatched.
+		mtx sync.Mutex
+		buf []byte
+	}
+
+	// Option represents a function that modifies a property of the client.
+	Option func(c *Client)
+
+	// Chunk represents a chunk of data which can be requested in a query. Chunks can be combined with a bitwise OR.
+	Chunk byte
+)
+
+const (
+	// ChunkServerInfo requests the server info chunk.
+	ChunkServerInfo = Chunk(0x1)
+
+	// ChunkServerRules requests the server rules chunk.
+	ChunkServerRules = Chunk(0x2)
+
+	// ChunkPlayerInfo requests the player info chunk.
+	ChunkPlayerInfo = Chunk(0x4)
+
+	// ChunkTeamInfo requests the team info chunk.
+	ChunkTeamInfo = Chunk(0x8)
+
+	// ChunkMetrics requests the metrics chunk. Metrics are only supported in SQP version 2.
+	ChunkMetrics = Chunk(0x10)
+
+	// ChunkAll requests every chunk.
+	ChunkAll = ChunkServerInfo | ChunkServerRules | ChunkPlayerInfo | ChunkTeamInfo | ChunkMetrics
+)
+
+const (
+	// DefaultTimeout is the default time allowed for a query to complete, if the context has no deadline.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultVersion is the default SQP version requested.
+	DefaultVersion = uint16(2)
+
+	// maxPacketSize is the size of the buffer packets are read into.
+	maxPacketSize = 64 * 1024
+)
+
+// WithTimeout sets the time allowed for a query to complete, if the context has no deadline.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithVersion sets the SQP version requested.
+func WithVersion(version uint16) Option {
+	return func(c *Client) {
+		c.version = version
+	}
+}
+
+// New creates a new client which queries the server at address, in the form "host:port".
+func New(address string, opts ...Option) (*Client, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:    conn,
+		timeout: DefaultTimeout,
+		version: DefaultVersion,
+		buf:     make([]byte, maxPacketSize),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Query performs the challenge handshake and requests the provided chunks from the server. If the query does not
+// complete before the deadline of ctx, or the client timeout if ctx has no deadline, an error wrapping
+// context.DeadlineExceeded is returned.
+func (c *Client) Query(ctx context.Context, chunks Chunk) (*Response, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.timeout)
+	}
+
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	challenge, err := c.challenge(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := []byte{1}
+	req = binary.BigEndian.AppendUint32(req, challenge)
+	req = binary.BigEndian.AppendUint16(req, c.version)
+	req = append(req, byte(chunks))
+
+	if _, err = c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	payload, err := c.readPayload(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	// Metrics are not returned prior to SQP version 2.
+	if c.version < 2 {
+		chunks &^= ChunkMetrics
+	}
+
+	return decodeResponse(payload, chunks)
+}
+
+// ServerInfo requests the server info chunk from the server.
+func (c *Client) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	resp, err := c.Query(ctx, ChunkServerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.ServerInfo, nil
+}
+
+// Rules requests the server rules chunk from the server.
+func (c *Client) Rules(ctx context.Context) (map[string]any, error) {
+	resp, err := c.Query(ctx, ChunkServerRules)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Rules, nil
+}
+
+// Players requests the player info chunk from the server.
+func (c *Client) Players(ctx context.Context) ([]Player, error) {
+	resp, err := c.Query(ctx, ChunkPlayerInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Players, nil
+}
+
+// Teams requests the team info chunk from the server.
+func (c *Client) Teams(ctx context.Context) ([]Team, error) {
+	resp, err := c.Query(ctx, ChunkTeamInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Teams, nil
+}
+
+// Metrics requests the metrics chunk from the server.
+func (c *Client) Metrics(ctx context.Context) ([]float32, error) {
+	resp, err := c.Query(ctx, ChunkMetrics)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Metrics, nil
+}
+
+// challenge requests a challenge from the server.
+func (c *Client) challenge(ctx context.Context) (uint32, error) {
+	if _, err := c.conn.Write([]byte{0, 0, 0, 0, 0}); err != nil {
+		return 0, err
+	}
+
+	for {
+		packet, err := c.read(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		// Ignore any late responses to previous queries.
+		if len(packet) == 5 && packet[0] == 0 {
+			return binary.BigEndian.Uint32(packet[1:5]), nil
+		}
+	}
+}
+
+// readPayload reads the response to a query with the provided challenge, reassembling the payload if it is split
+// across multiple packets.
+func (c *Client) readPayload(ctx context.Context, challenge uint32) ([]byte, error) {
+	var parts [][]byte
+	received := 0
+
+	for {
+		packet, err := c.read(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Ignore any late challenge responses.
+		if len(packet) > 0 && packet[0] == 0 {
+			continue
+		}
+
+		h, payload, err := decodeHeader(packet)
+		if err != nil {
+			return nil, err
+		}
+
+		// Ignore any late responses to previous queries.
+		if h.Challenge != challenge {
+			continue
+		}
+
+		if h.CurrentPacketNum > h.LastPacketNum {
+			return nil, ErrInvalidResponse
+		}
+
+		if parts == nil {
+			parts = make([][]byte, int(h.LastPacketNum)+1)
+		}
+
+		if int(h.LastPacketNum) != len(parts)-1 {
+			return nil, ErrInvalidResponse
+		}
+
+		if parts[h.CurrentPacketNum] == nil {
+			parts[h.CurrentPacketNum] = append([]byte{}, payload...)
+			received++
+		}
+
+		if received == len(parts) {
+			var full []byte
+			for _, p := range parts {
+				full = append(full, p...)
+			}
+
+			return full, nil
+		}
+	}
+}
+
+// read reads a single packet from the server.
+func (c *Client) read(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	n, err := c.conn.Read(c.buf)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+		}
+
+		return nil, err
+	}
+
+	return c.buf[:n], nil
+}
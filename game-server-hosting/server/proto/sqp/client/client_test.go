@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+// serve responds to queries on a local UDP endpoint with the provided responder, returning the endpoint address.
+func serve(t *testing.T, r proto.QueryResponder) string {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+
+			resp, err := r.Respond(addr.String(), buf[:n])
+			if err != nil {
+				continue
+			}
+
+			if _, err = conn.WriteToUDP(resp, addr); err != nil {
+				return
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func Test_Query(t *testing.T) {
+	t.Parallel()
+
+	r, err := sqp.NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     8,
+		ServerName:     "foo",
+		GameType:       "ctf",
+		Map:            "bar",
+		Port:           9000,
+		Metrics:        []float32{1.5, 2},
+		Players: []proto.QueryPlayer{
+			{Name: "alice", Score: -3, Ping: 20, Fields: map[string]any{"kills": uint16(4)}},
+		},
+		Teams: []proto.QueryTeam{
+			{Name: "red", Score: 10},
+		},
+		Rules: map[string]any{"round": uint8(2), "mode": "ctf"},
+	})
+	require.NoError(t, err)
+
+	c, err := New(serve(t, r), WithTimeout(time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+
+	resp, err := c.Query(context.Background(), ChunkAll)
+	require.NoError(t, err)
+	require.Equal(t, &Response{
+		ServerInfo: &ServerInfo{
+			CurrentPlayers: 1,
+			MaxPlayers:     8,
+			ServerName:     "foo",
+			GameType:       "ctf",
+			GameMap:        "bar",
+			Port:           9000,
+		},
+		Rules: map[string]any{"round": uint8(2), "mode": "ctf"},
+		Players: []Player{
+			{Name: "alice", Score: -3, Ping: 20, Fields: map[string]any{"kills": uint16(4)}},
+		},
+		Teams: []Team{
+			{Name: "red", Score: 10, Fields: map[string]any{}},
+		},
+		Metrics: []float32{1.5, 2},
+	}, resp)
+
+	info, err := c.ServerInfo(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "foo", info.ServerName)
+
+	metrics, err := c.Metrics(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []float32{1.5, 2}, metrics)
+}
+
+func Test_Query_version1(t *testing.T) {
+	t.Parallel()
+
+	r, err := sqp.NewQueryResponder(&proto.QueryState{
+		Metrics: []float32{1},
+	})
+	require.NoError(t, err)
+
+	c, err := New(serve(t, r), WithVersion(1))
+	require.NoError(t, err)
+	defer c.Close()
+
+	// Metrics are not supported prior to version 2.
+	resp, err := c.Query(context.Background(), ChunkServerInfo|ChunkMetrics)
+	require.NoError(t, err)
+	require.NotNil(t, resp.ServerInfo)
+	require.Nil(t, resp.Metrics)
+}
+
+func Test_Query_timeout(t *testing.T) {
+	t.Parallel()
+
+	// Nothing responds on this endpoint.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	c, err := New(conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.Query(ctx, ChunkServerInfo)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_readPayload_split(t *testing.T) {
+	t.Parallel()
+
+	server, client := net.Pipe()
+	defer server.Close()
+
+	c := &Client{conn: client, buf: make([]byte, maxPacketSize)}
+	defer c.Close()
+
+	// Packets may arrive out of order, and late responses to other queries are ignored.
+	go func() {
+		server.Write([]byte{1, 0, 0, 0, 2, 0, 1, 1, 1, 0, 2, 'c', 'd'})
+		server.Write([]byte{1, 0, 0, 0, 1, 0, 1, 0, 0, 0, 1, 'x'})
+		server.Write([]byte{1, 0, 0, 0, 2, 0, 1, 0, 1, 0, 2, 'a', 'b'})
+	}()
+
+	payload, err := c.readPayload(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, []byte("abcd"), payload)
+}
+
+func Test_decodeResponse_invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := decodeResponse([]byte{0, 0, 0, 10, 0}, ChunkServerInfo)
+	require.ErrorIs(t, err, ErrInvalidResponse)
+
+	_, err = decodeResponse([]byte{0, 0, 0, 1, 0}, ChunkServerInfo)
+	require.ErrorIs(t, err, ErrInvalidResponse)
+}
@@ -0,0 +1,8 @@
+package client
+
+import (
+	"errors"
+)
+
+// ErrInvalidResponse is an error which specifies the server responded with a malformed packet.
+var ErrInvalidResponse = errors.New("invalid response")
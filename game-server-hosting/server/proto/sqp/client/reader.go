@@ -0,0 +1,119 @@
+package client
+
+import (
+	"encoding/binary"
+)
+
+// reader reads values in the SQP wire format from a buffer. Once a read fails, all subsequent reads return zero
+// values and err is set.
+type reader struct {
+	buf []byte
+	err error
+}
+
+// next returns the next n bytes of the buffer.
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if len(r.buf) < n {
+		r.err = ErrInvalidResponse
+		return nil
+	}
+
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+// byte reads a single byte.
+func (r *reader) byte() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+
+	return 0
+}
+
+// uint16 reads a big-endian uint16.
+func (r *reader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+
+	return 0
+}
+
+// uint32 reads a big-endian uint32.
+func (r *reader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+
+	return 0
+}
+
+// uint64 reads a big-endian uint64.
+func (r *reader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+
+	return 0
+}
+
+// string reads a string prefixed with a single byte length.
+func (r *reader) string() string {
+	return string(r.next(int(r.byte())))
+}
+
+// value reads a value of the provided dynamic type.
+func (r *reader) value(t byte) any {
+	switch t {
+	case dynamicTypeByte:
+		return r.byte()
+	case dynamicTypeUint16:
+		return r.uint16()
+	case dynamicTypeUint32:
+		return r.uint32()
+	case dynamicTypeUint64:
+		return r.uint64()
+	case dynamicTypeString:
+		return r.string()
+	}
+
+	if r.err == nil {
+		r.err = ErrInvalidResponse
+	}
+
+	return nil
+}
+
+// chunk returns a reader for the next chunk, which is prefixed with a uint32 length.
+func (r *reader) chunk() *reader {
+	n := r.uint32()
+	if r.err != nil {
+		return &reader{err: r.err}
+	}
+
+	if uint64(n) > uint64(len(r.buf)) {
+		r.err = ErrInvalidResponse
+		return &reader{err: r.err}
+	}
+
+	return &reader{buf: r.next(int(n))}
+}
+
+// done returns any error encountered while reading, or ErrInvalidResponse if any of the buffer remains unread.
+func (r *reader) done() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if len(r.buf) > 0 {
+		return ErrInvalidResponse
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+type (
+	// Response represents the response to a query. Only the requested chunks are set.
+	Response struct {
+		ServerInfo *ServerInfo
+		Rules      map[string]any
+		Players    []Player
+		Teams      []Team
+		Metrics    []float32
+	}
+
+	// ServerInfo represents the server info chunk of a query response.
+	ServerInfo struct {
+		CurrentPlayers uint16
+		MaxPlayers     uint16
+		ServerName     string
+		GameType       string
+		BuildID        string
+		GameMap        string
+		Port           uint16
+	}
+
+	// Player represents a single player in the player info chunk of a query response.
+	Player struct {
+		Name  string
+		Score int32
+		Ping  uint16
+
+		// Fields holds any additional fields reported for the player, keyed by field name.
+		Fields map[string]any
+	}
+
+	// Team represents a single team in the team info chunk of a query response.
+	Team struct {
+		Name  string
+		Score int32
+
+		// Fields holds any additional fields reported for the team, keyed by field name.
+		Fields map[string]any
+	}
+
+	// header represents the header of a query response packet.
+	header struct {
+		Challenge        uint32
+		CurrentPacketNum byte
+		LastPacketNum    byte
+	}
+)
+
+const (
+	// headerSize is the size of a query response packet header.
+	headerSize = 11
+
+	// Value types in dynamically-typed chunks.
+	dynamicTypeByte   = byte(0)
+	dynamicTypeUint16 = byte(1)
+	dynamicTypeUint32 = byte(2)
+	dynamicTypeUint64 = byte(3)
+	dynamicTypeString = byte(4)
+)
+
+// decodeHeader decodes the header of a query response packet, returning the payload which follows it.
+func decodeHeader(packet []byte) (header, []byte, error) {
+	if len(packet) < headerSize || packet[0] != 1 {
+		return header{}, nil, ErrInvalidResponse
+	}
+
+	h := header{
+		Challenge:        binary.BigEndian.Uint32(packet[1:5]),
+		CurrentPacketNum: packet[7],
+		LastPacketNum:    packet[8],
+	}
+
+	payloadLength := int(binary.BigEndian.Uint16(packet[9:11]))
+	if len(packet)-headerSize < payloadLength {
+		return header{}, nil, ErrInvalidResponse
+	}
+
+	return h, packet[headerSize : headerSize+payloadLength], nil
+}
+
+// decodeResponse decodes the chunks in a query response payload. Chunks are present in the order of their flags.
+func decodeResponse(payload []byte, chunks Chunk) (*Response, error) {
+	resp := &Response{}
+	r := &reader{buf: payload}
+
+	if chunks&ChunkServerInfo != 0 {
+		cr := r.chunk()
+		resp.ServerInfo = &ServerInfo{
+			CurrentPlayers: cr.uint16(),
+			MaxPlayers:     cr.uint16(),
+			ServerName:     cr.string(),
+			GameType:       cr.string(),
+			BuildID:        cr.string(),
+			GameMap:        cr.string(),
+			Port:           cr.uint16(),
+		}
+
+		if err := cr.done(); err != nil {
+			return nil, fmt.Errorf("server info: %w", err)
+		}
+	}
+
+	if chunks&ChunkServerRules != 0 {
+		cr := r.chunk()
+		resp.Rules = map[string]any{}
+		for cr.err == nil && len(cr.buf) > 0 {
+			key := cr.string()
+			resp.Rules[key] = cr.value(cr.byte())
+		}
+
+		if err := cr.done(); err != nil {
+			return nil, fmt.Errorf("server rules: %w", err)
+		}
+	}
+
+	if chunks&ChunkPlayerInfo != 0 {
+		rows, err := decodeTable(r.chunk())
+		if err != nil {
+			return nil, fmt.Errorf("player info: %w", err)
+		}
+
+		resp.Players = make([]Player, 0, len(rows))
+		for _, row := range rows {
+			p := Player{Fields: map[string]any{}}
+			for k, v := range row {
+				switch k {
+				case "name":
+					p.Name, _ = v.(string)
+				case "score":
+					score, _ := v.(uint32)
+					p.Score = int32(score)
+				case "ping":
+					p.Ping, _ = v.(uint16)
+				default:
+					p.Fields[k] = v
+				}
+			}
+
+			resp.Players = append(resp.Players, p)
+		}
+	}
+
+	if chunks&ChunkTeamInfo != 0 {
+		rows, err := decodeTable(r.chunk())
+		if err != nil {
+			return nil, fmt.Errorf("team info: %w", err)
+		}
+
+		resp.Teams = make([]Team, 0, len(rows))
+		for _, row := range rows {
+			t := Team{Fields: map[string]any{}}
+			for k, v := range row {
+				switch k {
+				case "name":
+					t.Name, _ = v.(string)
+				case "score":
+					score, _ := v.(uint32)
+					t.Score = int32(score)
+				default:
+					t.Fields[k] = v
+				}
+			}
+
+			resp.Teams = append(resp.Teams, t)
+		}
+	}
+
+	if chunks&ChunkMetrics != 0 {
+		cr := r.chunk()
+		count := int(cr.byte())
+		resp.Metrics = make([]float32, 0, count)
+		for i := 0; i < count; i++ {
+			resp.Metrics = append(resp.Metrics, math.Float32frombits(cr.uint32()))
+		}
+
+		if err := cr.done(); err != nil {
+			return nil, fmt.Errorf("metrics: %w", err)
+		}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return resp, nil
+}
+
+// decodeTable decodes a table chunk, such as the player info chunk, into rows of values keyed by field name.
+func decodeTable(r *reader) ([]map[string]any, error) {
+	count := int(r.uint16())
+	fieldCount := int(r.byte())
+
+	type field struct {
+		name string
+		typ  byte
+	}
+
+	fields := make([]field, 0, fieldCount)
+	for i := 0; i < fieldCount && r.err == nil; i++ {
+		fields = append(fields, field{name: r.string(), typ: r.byte()})
+	}
+
+	rows := make([]map[string]any, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		row := make(map[string]any, len(fields))
+		for _, f := range fields {
+			row[f.name] = r.value(f.typ)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,192 @@
+package sqp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+type (
+	// dynamicType represents the type of a value in a dynamically-typed SQP chunk.
+	dynamicType byte
+
+	// sqpField describes a named, typed field in a dynamically-typed SQP chunk.
+	sqpField struct {
+		Name string
+		Type dynamicType
+	}
+
+	// sqpTable holds the data for chunks which are made up of a field descriptor header followed by
+	// rows of values, such as the player and team info chunks. Rows hold values as returned by toDynamicValue.
+	sqpTable struct {
+		Fields []sqpField
+		Rows   [][]any
+	}
+)
+
+const (
+	dynamicTypeByte = dynamicType(iota)
+	dynamicTypeUint16
+	dynamicTypeUint32
+	dynamicTypeUint64
+	dynamicTypeString
+)
+
+// dynamicTypeOf returns the SQP type used to encode the provided value.
+func dynamicTypeOf(v any) (dynamicType, error) {
+	switch v.(type) {
+	case bool, uint8, int8:
+		return dynamicTypeByte, nil
+	case uint16, int16:
+		return dynamicTypeUint16, nil
+	case uint32, int32:
+		return dynamicTypeUint32, nil
+	case uint64, int64, uint, int:
+		return dynamicTypeUint64, nil
+	case string:
+		return dynamicTypeString, nil
+	}
+
+	return 0, fmt.Errorf("%w: %T", errUnsupportedFieldType, v)
+}
+
+// toDynamicValue converts v to the Go type which is written to the wire for the SQP type t. A nil value is
+// converted to the zero value of t.
+func toDynamicValue(t dynamicType, v any) (any, error) {
+	if v == nil {
+		switch t {
+		case dynamicTypeByte:
+			return byte(0), nil
+		case dynamicTypeUint16:
+			return uint16(0), nil
+		case dynamicTypeUint32:
+			return uint32(0), nil
+		case dynamicTypeUint64:
+			return uint64(0), nil
+		case dynamicTypeString:
+			return "", nil
+		}
+	}
+
+	vt, err := dynamicTypeOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if vt != t {
+		return nil, fmt.Errorf("%w: %T", errMismatchedFieldType, v)
+	}
+
+	switch x := v.(type) {
+	case bool:
+		if x {
+			return byte(1), nil
+		}
+		return byte(0), nil
+	case int8:
+		return byte(x), nil
+	case int16:
+		return uint16(x), nil
+	case int32:
+		return uint32(x), nil
+	case int64:
+		return uint64(x), nil
+	case uint:
+		return uint64(x), nil
+	case int:
+		return uint64(x), nil
+	}
+
+	return v, nil
+}
+
+// appendDynamicValue appends a value, as returned by toDynamicValue, to dst.
+func appendDynamicValue(dst []byte, v any) []byte {
+	switch x := v.(type) {
+	case byte:
+		return append(dst, x)
+	case uint16:
+		return binary.BigEndian.AppendUint16(dst, x)
+	case uint32:
+		return binary.BigEndian.AppendUint32(dst, x)
+	case uint64:
+		return binary.BigEndian.AppendUint64(dst, x)
+	case string:
+		return appendString(dst, x)
+	}
+
+	return dst
+}
+
+// customFields returns the union of field names across the provided field maps in sorted order, along with
+// the SQP type of each field. The type of a field is taken from the first map in which it appears.
+func customFields(fieldMaps []map[string]any) ([]sqpField, error) {
+	types := map[string]dynamicType{}
+	for _, m := range fieldMaps {
+		for k, v := range m {
+			if _, ok := types[k]; ok || v == nil {
+				continue
+			}
+
+			t, err := dynamicTypeOf(v)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", k, err)
+			}
+
+			types[k] = t
+		}
+	}
+
+	fields := make([]sqpField, 0, len(types))
+	for k, t := range types {
+		fields = append(fields, sqpField{Name: k, Type: t})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	return fields, nil
+}
+
+// addRow converts the provided values to the types of the table fields and adds them as a row. Missing values are
+// converted to the zero value of their field type.
+func (t *sqpTable) addRow(values ...any) error {
+	row := make([]any, len(t.Fields))
+	for i, f := range t.Fields {
+		var v any
+		if i < len(values) {
+			v = values[i]
+		}
+
+		wv, err := toDynamicValue(f.Type, v)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+
+		row[i] = wv
+	}
+
+	t.Rows = append(t.Rows, row)
+
+	return nil
+}
+
+// AppendTo appends the table in the SQP wire format, excluding the chunk length, to dst.
+func (t *sqpTable) AppendTo(dst []byte) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(t.Rows)))
+	dst = append(dst, byte(len(t.Fields)))
+
+	for _, f := range t.Fields {
+		dst = appendString(dst, f.Name)
+		dst = append(dst, byte(f.Type))
+	}
+
+	for _, row := range t.Rows {
+		for _, v := range row {
+			dst = appendDynamicValue(dst, v)
+		}
+	}
+
+	return dst
+}
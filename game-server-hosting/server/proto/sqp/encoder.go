@@ -1,25 +1,26 @@
 package sqp
 
 import (
-	"bytes"
 	"encoding/binary"
 )
 
-type (
-	// encoder is a struct which implements proto.WireEncoder.
-	encoder struct{}
-)
-
-// WriteString writes a string to the provided buffer.
-func (e *encoder) WriteString(resp *bytes.Buffer, s string) error {
-	if err := binary.Write(resp, binary.BigEndian, byte(len(s))); err != nil {
-		return err
+// appendString appends a length-prefixed string to dst. As the length prefix is a single byte, strings longer than
+// 255 bytes are truncated.
+func appendString(dst []byte, s string) []byte {
+	if len(s) > 0xFF {
+		s = s[:0xFF]
 	}
 
-	return binary.Write(resp, binary.BigEndian, []byte(s))
+	dst = append(dst, byte(len(s)))
+	return append(dst, s...)
+}
+
+// beginChunk reserves space for a chunk length at the end of dst, returning the position of the reservation.
+func beginChunk(dst []byte) ([]byte, int) {
+	return append(dst, 0, 0, 0, 0), len(dst)
 }
 
-// Write writes arbitrary data to the provided buffer.
-func (e *encoder) Write(resp *bytes.Buffer, v interface{}) error {
-	return binary.Write(resp, binary.BigEndian, v)
+// endChunk writes the length of the chunk which began at pos, as returned by beginChunk.
+func endChunk(dst []byte, pos int) {
+	binary.BigEndian.PutUint32(dst[pos:pos+4], uint32(len(dst)-pos-4))
 }
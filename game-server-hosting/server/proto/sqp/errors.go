@@ -13,8 +13,11 @@ type (
 )
 
 var (
-	errInvalidPacketLength = errors.New("invalid packet length")
-	errUnsupportedQuery    = errors.New("unsupported query")
+	errInvalidPacketLength  = errors.New("invalid packet length")
+	errUnsupportedQuery     = errors.New("unsupported query")
+	errUnsupportedFieldType = errors.New("unsupported field type")
+	errMismatchedFieldType  = errors.New("field type does not match other values for the same field")
+	errPayloadTooLarge      = errors.New("response payload too large")
 )
 
 // NewUnsupportedSQPVersionError returns a new instance of UnsupportedSQPVersionError.
@@ -1,6 +1,7 @@
 package sqp
 
 import (
+	"encoding/binary"
 	"math"
 
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
@@ -9,7 +10,6 @@ import (
 type (
 	// sqpMetricsInfo holds the server metrics chunk data.
 	sqpMetricsInfo struct {
-		Count  byte
 		Values []float32
 	}
 )
@@ -18,19 +18,27 @@ type (
 const MaxMetrics = 10
 
 // queryStateToMetrics converts metrics data in provided query state to sqpMetricsInfo.
-func queryStateToMetrics(qs *proto.QueryState) *sqpMetricsInfo {
-	l := byte(math.Min(float64(len(qs.Metrics)), float64(MaxMetrics)))
-	m := &sqpMetricsInfo{
-		Count: l,
+func queryStateToMetrics(qs *proto.QueryState) sqpMetricsInfo {
+	if qs == nil {
+		return sqpMetricsInfo{}
 	}
 
-	m.Values = make([]float32, l)
-	copy(m.Values, qs.Metrics)
+	values := qs.Metrics
+	if len(values) > MaxMetrics {
+		values = values[:MaxMetrics]
+	}
 
-	return m
+	return sqpMetricsInfo{
+		Values: values,
+	}
 }
 
-// Size returns the number of bytes QueryResponder will use on the wire.
-func (m sqpMetricsInfo) Size() uint32 {
-	return uint32(1 + len(m.Values)*4)
+// AppendTo appends the metrics chunk data in the SQP wire format to dst.
+func (m sqpMetricsInfo) AppendTo(dst []byte) []byte {
+	dst = append(dst, byte(len(m.Values)))
+	for _, v := range m.Values {
+		dst = binary.BigEndian.AppendUint32(dst, math.Float32bits(v))
+	}
+
+	return dst
 }
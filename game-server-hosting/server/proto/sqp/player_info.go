@@ -0,0 +1,53 @@
+package sqp
+
+import (
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+// playerInfoFields are the fields present for every player in the player info chunk, in wire order.
+var playerInfoFields = []sqpField{
+	{Name: "name", Type: dynamicTypeString},
+	{Name: "score", Type: dynamicTypeUint32},
+	{Name: "ping", Type: dynamicTypeUint16},
+}
+
+// queryStateToPlayerInfo converts player data in provided query state to a table suitable for the player info chunk.
+// Scores are written as the two's complement representation of the signed value.
+func queryStateToPlayerInfo(qs *proto.QueryState) (*sqpTable, error) {
+	t := &sqpTable{
+		Fields: playerInfoFields,
+	}
+
+	if qs == nil {
+		return t, nil
+	}
+
+	players := qs.Players
+	fieldMaps := make([]map[string]any, len(players))
+	for i := range players {
+		fieldMaps[i] = players[i].Fields
+	}
+
+	custom, err := customFields(fieldMaps)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Fields = append(append([]sqpField{}, playerInfoFields...), custom...)
+	t.Rows = make([][]any, 0, len(players))
+
+	for _, p := range players {
+		row := make([]any, 0, len(t.Fields))
+		row = append(row, p.Name, uint32(p.Score), p.Ping)
+
+		for _, f := range custom {
+			row = append(row, p.Fields[f.Name])
+		}
+
+		if err = t.addRow(row...); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
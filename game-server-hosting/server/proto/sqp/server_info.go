@@ -1,7 +1,7 @@
 package sqp
 
 import (
-	"sync/atomic"
+	"encoding/binary"
 
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
 )
@@ -20,17 +20,17 @@ type (
 )
 
 // queryStateToServerInfo converts proto.QueryState to sqpServerInfo.
-func queryStateToServerInfo(qs *proto.QueryState) *sqpServerInfo {
+func queryStateToServerInfo(qs *proto.QueryState) sqpServerInfo {
 	if qs == nil {
-		return &sqpServerInfo{
+		return sqpServerInfo{
 			ServerName: "n/a",
 			GameType:   "n/a",
 			GameMap:    "n/a",
 		}
 	}
 
-	return &sqpServerInfo{
-		CurrentPlayers: uint16(atomic.LoadInt32(&qs.CurrentPlayers)),
+	return sqpServerInfo{
+		CurrentPlayers: uint16(qs.CurrentPlayers),
 		MaxPlayers:     uint16(qs.MaxPlayers),
 		ServerName:     qs.ServerName,
 		GameType:       qs.GameType,
@@ -39,15 +39,13 @@ func queryStateToServerInfo(qs *proto.QueryState) *sqpServerInfo {
 	}
 }
 
-// Size returns the number of bytes sqpServerInfo will use on the wire.
-func (si sqpServerInfo) Size() uint32 {
-	return uint32(
-		2 + // CurrentPlayers
-			2 + // MaxPlayers
-			len([]byte(si.ServerName)) + 1 +
-			len([]byte(si.GameType)) + 1 +
-			len([]byte(si.BuildID)) + 1 +
-			len([]byte(si.GameMap)) + 1 +
-			2, // Port
-	)
+// AppendTo appends the server info chunk data in the SQP wire format to dst.
+func (si sqpServerInfo) AppendTo(dst []byte) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, si.CurrentPlayers)
+	dst = binary.BigEndian.AppendUint16(dst, si.MaxPlayers)
+	dst = appendString(dst, si.ServerName)
+	dst = appendString(dst, si.GameType)
+	dst = appendString(dst, si.BuildID)
+	dst = appendString(dst, si.GameMap)
+	return binary.BigEndian.AppendUint16(dst, si.Port)
 }
@@ -0,0 +1,73 @@
+package sqp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+type (
+	// sqpRule holds a single rule in the server rules chunk. Value holds a value as returned by toDynamicValue.
+	sqpRule struct {
+		Key   string
+		Type  dynamicType
+		Value any
+	}
+
+	// sqpServerRules holds the server rules chunk data.
+	sqpServerRules []sqpRule
+)
+
+// queryStateToServerRules converts the rules in provided query state to sqpServerRules. Rules are ordered by their key.
+// SQP has no floating point type, so floating point values are encoded as strings.
+func queryStateToServerRules(qs *proto.QueryState) (sqpServerRules, error) {
+	if qs == nil {
+		return nil, nil
+	}
+
+	rules := qs.Rules
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	sr := make(sqpServerRules, 0, len(keys))
+	for _, k := range keys {
+		rule := rules[k]
+		switch f := rule.(type) {
+		case float32:
+			rule = strconv.FormatFloat(float64(f), 'g', -1, 32)
+		case float64:
+			rule = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+
+		t, err := dynamicTypeOf(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", k, err)
+		}
+
+		v, err := toDynamicValue(t, rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", k, err)
+		}
+
+		sr = append(sr, sqpRule{Key: k, Type: t, Value: v})
+	}
+
+	return sr, nil
+}
+
+// AppendTo appends the server rules chunk data in the SQP wire format to dst.
+func (sr sqpServerRules) AppendTo(dst []byte) []byte {
+	for _, r := range sr {
+		dst = appendString(dst, r.Key)
+		dst = append(dst, byte(r.Type))
+		dst = appendDynamicValue(dst, r.Value)
+	}
+
+	return dst
+}
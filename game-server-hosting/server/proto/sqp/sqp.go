@@ -3,6 +3,7 @@ package sqp
 import (
 	"bytes"
 	"encoding/binary"
+	"sync/atomic"
 
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
 )
@@ -11,7 +12,10 @@ type (
 	// QueryResponder represents a responder capable of responding to SQP-formatted queries.
 	QueryResponder struct {
 		*proto.QueryBase
-		enc *encoder
+
+		// cache holds encoded query responses for the current snapshot of the query state, keyed by the
+		// requested chunks. It is nil if responses are not cached.
+		cache *proto.ResponseCache
 	}
 
 	// challengeWireFormat describes the format of an SQP challenge response.
@@ -20,29 +24,42 @@ type (
 		Challenge uint32
 	}
 
-	// queryWireFormat describes the format of an SQP query response.
-	queryWireFormat struct {
-		Header            byte
-		Challenge         uint32
-		SQPVersion        uint16
-		CurrentPacketNum  byte
-		LastPacketNum     byte
-		PayloadLength     uint16
-		ServerInfoLength  *uint32
-		ServerInfo        *sqpServerInfo
-		MetricsInfoLength *uint32
-		MetricsInfo       *sqpMetricsInfo
+	// queryHeaderWireFormat describes the format of the header of an SQP query response, which precedes the chunks.
+	queryHeaderWireFormat struct {
+		Header           byte
+		Challenge        uint32
+		SQPVersion       uint16
+		CurrentPacketNum byte
+		LastPacketNum    byte
+		PayloadLength    uint16
 	}
 )
 
+// queryHeaderSize is the size of queryHeaderWireFormat on the wire.
+const queryHeaderSize = 11
+
 // NewQueryResponder returns creates a new responder capable of responding
-// to SQP-formatted queries.
+// to SQP-formatted queries, using the provided query state. The state is read for each query, so it may be modified
+// between queries.
 func NewQueryResponder(state *proto.QueryState) (*QueryResponder, error) {
 	q := &QueryResponder{
 		QueryBase: &proto.QueryBase{
 			State: state,
 		},
-		enc: &encoder{},
+	}
+
+	return q, nil
+}
+
+// NewQueryResponderFromSnapshot creates a new responder capable of responding to SQP-formatted queries,
+// using the query state snapshot currently held by the provided pointer. Snapshots must not be modified, as
+// responses are cached until the snapshot is replaced.
+func NewQueryResponderFromSnapshot(state *atomic.Pointer[proto.QueryState]) (*QueryResponder, error) {
+	q := &QueryResponder{
+		QueryBase: &proto.QueryBase{
+			Snapshots: state,
+		},
+		cache: &proto.ResponseCache{},
 	}
 
 	return q, nil
@@ -50,12 +67,21 @@ func NewQueryResponder(state *proto.QueryState) (*QueryResponder, error) {
 
 // Respond writes a query response to the requester in the SQP wire protocol.
 func (q *QueryResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	return q.AppendResponse(nil, clientAddress, buf)
+}
+
+// AppendResponse appends a query response in the SQP wire protocol to dst.
+func (q *QueryResponder) AppendResponse(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
+	if len(buf) < 5 {
+		return nil, errInvalidPacketLength
+	}
+
 	switch {
 	case isChallenge(buf):
-		return q.handleChallenge(clientAddress)
+		return q.handleChallenge(dst, clientAddress)
 
 	case isQuery(buf):
-		return q.handleQuery(clientAddress, buf)
+		return q.handleQuery(dst, clientAddress, buf)
 	}
 
 	return nil, errUnsupportedQuery
@@ -72,30 +98,20 @@ func isQuery(buf []byte) bool {
 }
 
 // handleChallenge handles an incoming challenge packet.
-func (q *QueryResponder) handleChallenge(clientAddress string) ([]byte, error) {
+func (q *QueryResponder) handleChallenge(dst []byte, clientAddress string) ([]byte, error) {
 	v, err := q.GenerateChallenge(clientAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	resp := bytes.NewBuffer(nil)
-	err = proto.WireWrite(
-		resp,
-		q.enc,
-		challengeWireFormat{
-			Header:    0,
-			Challenge: v,
-		},
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp.Bytes(), nil
+	return challengeWireFormat{
+		Header:    0,
+		Challenge: v,
+	}.AppendTo(dst), nil
 }
 
 // handleQuery handles an incoming query packet.
-func (q *QueryResponder) handleQuery(clientAddress string, buf []byte) ([]byte, error) {
+func (q *QueryResponder) handleQuery(dst []byte, clientAddress string, buf []byte) ([]byte, error) {
 	if len(buf) < 8 {
 		return nil, errInvalidPacketLength
 	}
@@ -111,36 +127,119 @@ func (q *QueryResponder) handleQuery(clientAddress string, buf []byte) ([]byte,
 	}
 
 	requestedChunks := buf[7]
+
+	// Metrics supported in SQPv2.
+	if protocolVersion < 2 {
+		requestedChunks &^= 0x10
+	}
+
+	// Responses only differ by challenge until the query state changes, so a cached response is patched with the
+	// challenge of this request.
+	qs := q.Snapshot()
+	cached := qs != nil && q.cache != nil
+	if cached {
+		if resp, ok := q.cache.Get(qs, uint32(requestedChunks)); ok {
+			start := len(dst)
+			dst = append(dst, resp...)
+			binary.BigEndian.PutUint32(dst[start+1:], challenge)
+			return dst, nil
+		}
+	}
+
+	start := len(dst)
+	dst, err := appendQueryResponse(dst, qs, challenge, requestedChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		q.cache.Put(qs, uint32(requestedChunks), dst[start:])
+	}
+
+	return dst, nil
+}
+
+// appendQueryResponse appends a query response containing the requested chunks of the provided query state to dst.
+func appendQueryResponse(dst []byte, qs *proto.QueryState, challenge uint32, requestedChunks byte) ([]byte, error) {
 	wantsServerInfo := requestedChunks&0x1 == 1
+	wantsServerRules := requestedChunks&0x2 == 2
+	wantsPlayerInfo := requestedChunks&0x4 == 4
+	wantsTeamInfo := requestedChunks&0x8 == 8
 	wantsMetrics := requestedChunks&0x10 == 16
-	f := queryWireFormat{
-		Header:     1,
-		Challenge:  challenge,
-		SQPVersion: 1,
-	}
 
-	resp := bytes.NewBuffer(nil)
+	// Chunks are converted before anything is appended so that dst is left untouched on error.
+	var chunks []proto.WireAppender
 
 	if wantsServerInfo {
-		f.ServerInfo = queryStateToServerInfo(q.State)
-		size := f.ServerInfo.Size()
-		f.ServerInfoLength = &size
-		f.PayloadLength += uint16(*f.ServerInfoLength) + 4
+		chunks = append(chunks, queryStateToServerInfo(qs))
 	}
 
-	// Metrics supported in SQPv2.
-	if protocolVersion >= 2 {
-		if wantsMetrics {
-			f.MetricsInfo = queryStateToMetrics(q.State)
-			size := f.MetricsInfo.Size()
-			f.MetricsInfoLength = &size
-			f.PayloadLength += uint16(*f.MetricsInfoLength) + 4
+	if wantsServerRules {
+		rules, err := queryStateToServerRules(qs)
+		if err != nil {
+			return nil, err
 		}
+
+		chunks = append(chunks, rules)
 	}
 
-	if err := proto.WireWrite(resp, q.enc, f); err != nil {
-		return nil, err
+	if wantsPlayerInfo {
+		players, err := queryStateToPlayerInfo(qs)
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, players)
+	}
+
+	if wantsTeamInfo {
+		teams, err := queryStateToTeamInfo(qs)
+		if err != nil {
+			return nil, err
+		}
+
+		chunks = append(chunks, teams)
 	}
 
-	return resp.Bytes(), nil
+	if wantsMetrics {
+		chunks = append(chunks, queryStateToMetrics(qs))
+	}
+
+	start := len(dst)
+	dst = queryHeaderWireFormat{
+		Header:     1,
+		Challenge:  challenge,
+		SQPVersion: 1,
+	}.AppendTo(dst)
+
+	for _, c := range chunks {
+		var pos int
+		dst, pos = beginChunk(dst)
+		dst = c.AppendTo(dst)
+		endChunk(dst, pos)
+	}
+
+	payloadLength := len(dst) - start - queryHeaderSize
+	if payloadLength > 0xFFFF {
+		return nil, errPayloadTooLarge
+	}
+
+	binary.BigEndian.PutUint16(dst[start+queryHeaderSize-2:], uint16(payloadLength))
+
+	return dst, nil
+}
+
+// AppendTo appends the challenge response in the SQP wire format to dst.
+func (c challengeWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, c.Header)
+	return binary.BigEndian.AppendUint32(dst, c.Challenge)
+}
+
+// AppendTo appends the query response header in the SQP wire format to dst.
+func (h queryHeaderWireFormat) AppendTo(dst []byte) []byte {
+	dst = append(dst, h.Header)
+	dst = binary.BigEndian.AppendUint32(dst, h.Challenge)
+	dst = binary.BigEndian.AppendUint16(dst, h.SQPVersion)
+	dst = append(dst, h.CurrentPacketNum, h.LastPacketNum)
+	return binary.BigEndian.AppendUint16(dst, h.PayloadLength)
 }
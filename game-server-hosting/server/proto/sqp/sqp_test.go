@@ -278,3 +278,301 @@ func Test_Respond_mismatchedChallenge(t *testing.T) {
 	require.Nil(t, resp)
 	require.ErrorIs(t, err, proto.ErrChallengeMismatch)
 }
+
+func Test_Respond_PlayerInfo(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 2,
+		MaxPlayers:     2,
+		Players: []proto.QueryPlayer{
+			{ID: "a", Name: "foo", Score: -1, Ping: 20, Fields: map[string]any{"kills": uint16(3)}},
+			{ID: "b", Name: "ba", Score: 5, Ping: 30},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	addr := addrKey
+
+	// Challenge packet
+	resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	require.Equal(t, byte(0), resp[0])
+
+	// Query packet
+	resp, err = q.Respond(
+		addr,
+		bytes.Join(
+			[][]byte{
+				{1},
+				resp[1:5],    // challenge
+				{0, 1},       // SQP version
+				{0b00000100}, // Request chunks (player info only)
+			},
+			nil,
+		),
+	)
+	require.NoError(t, err)
+
+	playerInfo := bytes.Join(
+		[][]byte{
+			{0x0, 0x2}, // player count
+			{0x4},      // field count
+			{0x4}, []byte("name"), {0x4},
+			{0x5}, []byte("score"), {0x2},
+			{0x4}, []byte("ping"), {0x1},
+			{0x5}, []byte("kills"), {0x1},
+			{0x3}, []byte("foo"), {0xff, 0xff, 0xff, 0xff}, {0x0, 0x14}, {0x0, 0x3},
+			{0x2}, []byte("ba"), {0x0, 0x0, 0x0, 0x5}, {0x0, 0x1e}, {0x0, 0x0},
+		},
+		nil,
+	)
+
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{1},
+				resp[1:5],
+				resp[5:7],
+				{0},
+				{0},
+				{0x0, byte(len(playerInfo) + 4)},
+				// Player Info chunk
+				{0x0, 0x0, 0x0, byte(len(playerInfo))},
+				playerInfo,
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_PlayerInfo_unsupportedFieldType(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		Players: []proto.QueryPlayer{
+			{ID: "a", Name: "foo", Fields: map[string]any{"ratio": 1.5}},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := q.Respond(addrKey, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+
+	resp, err = q.Respond(addrKey, bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0b00000100}}, nil))
+	require.Nil(t, resp)
+	require.ErrorIs(t, err, errUnsupportedFieldType)
+}
+
+func Test_Respond_ServerRulesAndTeamInfo(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		Rules: map[string]any{
+			"round":   uint16(3),
+			"mode":    "ctf",
+			"gravity": 9.8,
+		},
+		Teams: []proto.QueryTeam{
+			{Name: "red", Score: 2},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, q)
+
+	addr := addrKey
+
+	// Challenge packet
+	resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	require.Equal(t, byte(0), resp[0])
+
+	// Query packet
+	resp, err = q.Respond(
+		addr,
+		bytes.Join(
+			[][]byte{
+				{1},
+				resp[1:5],    // challenge
+				{0, 1},       // SQP version
+				{0b00001010}, // Request chunks (server rules and team info)
+			},
+			nil,
+		),
+	)
+	require.NoError(t, err)
+
+	rules := bytes.Join(
+		[][]byte{
+			// Floating point values are encoded as strings.
+			{0x7}, []byte("gravity"), {0x4}, {0x3}, []byte("9.8"),
+			{0x4}, []byte("mode"), {0x4}, {0x3}, []byte("ctf"),
+			{0x5}, []byte("round"), {0x1}, {0x0, 0x3},
+		},
+		nil,
+	)
+
+	teams := bytes.Join(
+		[][]byte{
+			{0x0, 0x1}, // team count
+			{0x2},      // field count
+			{0x4}, []byte("name"), {0x4},
+			{0x5}, []byte("score"), {0x2},
+			{0x3}, []byte("red"), {0x0, 0x0, 0x0, 0x2},
+		},
+		nil,
+	)
+
+	require.Equal(
+		t,
+		bytes.Join(
+			[][]byte{
+				{1},
+				resp[1:5],
+				resp[5:7],
+				{0},
+				{0},
+				{0x0, byte(len(rules) + len(teams) + 8)},
+				// Server Rules chunk
+				{0x0, 0x0, 0x0, byte(len(rules))},
+				rules,
+				// Team Info chunk
+				{0x0, 0x0, 0x0, byte(len(teams))},
+				teams,
+			},
+			nil,
+		),
+		resp,
+	)
+}
+
+func Test_Respond_invalidPacketLength(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{})
+	require.NoError(t, err)
+
+	resp, err := q.Respond(addrKey, []byte{0, 0})
+	require.Nil(t, resp)
+	require.ErrorIs(t, err, errInvalidPacketLength)
+}
+
+func Test_AppendResponse(t *testing.T) {
+	t.Parallel()
+	q, err := NewQueryResponder(&proto.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     2,
+	})
+	require.NoError(t, err)
+
+	// Stateless challenges can be satisfied more than once.
+	require.NoError(t, q.EnableStatelessChallenges())
+
+	challenge, err := q.Respond(addrKey, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+
+	req := bytes.Join([][]byte{{1}, challenge[1:5], {0, 1}, {0b00000001}}, nil)
+	expected, err := q.Respond(addrKey, req)
+	require.NoError(t, err)
+
+	// The response is appended to, rather than overwriting, the provided buffer.
+	prefix := []byte{0xAA, 0xBB}
+	resp, err := q.AppendResponse(append([]byte{}, prefix...), addrKey, req)
+	require.NoError(t, err)
+	require.Equal(t, append(prefix, expected...), resp)
+}
+
+func Test_Respond_cached(t *testing.T) {
+	t.Parallel()
+	state := proto.NewQueryStatePointer(&proto.QueryState{
+		ServerName: "foo",
+	})
+	q, err := NewQueryResponderFromSnapshot(state)
+	require.NoError(t, err)
+	require.NoError(t, q.EnableStatelessChallenges())
+
+	query := func(addr string) []byte {
+		challenge, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+		require.NoError(t, err)
+
+		resp, err := q.Respond(addr, bytes.Join([][]byte{{1}, challenge[1:5], {0, 1}, {0b00000001}}, nil))
+		require.NoError(t, err)
+		require.Equal(t, challenge[1:5], resp[1:5])
+		return resp
+	}
+
+	first := query("client-addr:1")
+
+	// Cached responses are patched with the challenge of each request.
+	second := query("client-addr:2")
+	require.NotEqual(t, first[1:5], second[1:5])
+	require.Equal(t, first[5:], second[5:])
+
+	// The cached response is replaced once the snapshot is replaced, even if its revision is unchanged.
+	state.Store(&proto.QueryState{ServerName: "bar"})
+	third := query("client-addr:1")
+	require.NotEqual(t, first[5:], third[5:])
+	require.Contains(t, string(third), "bar")
+}
+
+func Test_Respond_stateModified(t *testing.T) {
+	t.Parallel()
+	state := &proto.QueryState{
+		Metrics: make([]float32, 1, MaxMetrics),
+	}
+	q, err := NewQueryResponder(state)
+	require.NoError(t, err)
+
+	query := func() []byte {
+		challenge, err := q.Respond(addrKey, []byte{0, 0, 0, 0, 0})
+		require.NoError(t, err)
+
+		resp, err := q.Respond(addrKey, bytes.Join([][]byte{{1}, challenge[1:5], {0, 2}, {0b00010000}}, nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	metricBytes := bytes.NewBuffer(nil)
+	require.NoError(t, binary.Write(metricBytes, binary.BigEndian, float32(0)))
+	require.True(t, bytes.HasSuffix(query(), metricBytes.Bytes()))
+
+	// Responses are not cached, so modifications to the state are seen by the next query.
+	state.Metrics[0] = 1.234
+	metricBytes.Reset()
+	require.NoError(t, binary.Write(metricBytes, binary.BigEndian, state.Metrics[0]))
+	require.True(t, bytes.HasSuffix(query(), metricBytes.Bytes()))
+}
+
+func Benchmark_AppendResponse(b *testing.B) {
+	q, err := NewQueryResponderFromSnapshot(proto.NewQueryStatePointer(&proto.QueryState{
+		CurrentPlayers: 2,
+		MaxPlayers:     16,
+		ServerName:     "Benchmark Server",
+		GameType:       "Deathmatch",
+		Map:            "de_dust2",
+		Port:           9000,
+		Metrics:        []float32{1, 2, 3},
+		Players: []proto.QueryPlayer{
+			{ID: "1", Name: "Player One", Score: 10, Ping: 20},
+			{ID: "2", Name: "Player Two", Score: 5, Ping: 40},
+		},
+		Rules: map[string]any{"friendlyfire": true, "round": 3},
+	}))
+	require.NoError(b, err)
+
+	require.NoError(b, q.EnableStatelessChallenges())
+
+	challenge, err := q.Respond(addrKey, []byte{0, 0, 0, 0, 0})
+	require.NoError(b, err)
+
+	req := bytes.Join([][]byte{{1}, challenge[1:5], {0, 2}, {0b00010111}}, nil)
+	dst := make([]byte, 0, 1024)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err = q.AppendResponse(dst[:0], addrKey, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
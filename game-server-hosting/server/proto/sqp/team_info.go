@@ -0,0 +1,52 @@
+package sqp
+
+import (
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+)
+
+// teamInfoFields are the fields present for every team in the team info chunk, in wire order.
+var teamInfoFields = []sqpField{
+	{Name: "name", Type: dynamicTypeString},
+	{Name: "score", Type: dynamicTypeUint32},
+}
+
+// queryStateToTeamInfo converts team data in provided query state to a table suitable for the team info chunk.
+// Scores are written as the two's complement representation of the signed value.
+func queryStateToTeamInfo(qs *proto.QueryState) (*sqpTable, error) {
+	t := &sqpTable{
+		Fields: teamInfoFields,
+	}
+
+	if qs == nil {
+		return t, nil
+	}
+
+	teams := qs.Teams
+	fieldMaps := make([]map[string]any, len(teams))
+	for i := range teams {
+		fieldMaps[i] = teams[i].Fields
+	}
+
+	custom, err := customFields(fieldMaps)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Fields = append(append([]sqpField{}, teamInfoFields...), custom...)
+	t.Rows = make([][]any, 0, len(teams))
+
+	for _, team := range teams {
+		row := make([]any, 0, len(t.Fields))
+		row = append(row, team.Name, uint32(team.Score))
+
+		for _, f := range custom {
+			row = append(row, team.Fields[f.Name])
+		}
+
+		if err = t.addRow(row...); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
@@ -4,8 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/a2s"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/gs4"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/multi"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/q3"
 	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
 )
 
@@ -13,6 +20,42 @@ type (
 	// QueryProtocol represents the query type the server uses.
 	// Documentation: https://docs.unity.com/game-server-hosting/en/manual/concepts/query-protocols
 	QueryProtocol string
+
+	// QueryBindMode represents the IP version(s) the query endpoint is bound on.
+	QueryBindMode int8
+
+	// QueryResponderFactory creates a responder for a query protocol, which responds to queries using the
+	// provided query state.
+	QueryResponderFactory func(state *proto.QueryState) (proto.QueryResponder, error)
+
+	// SnapshotQueryResponderFactory creates a responder for a query protocol, which responds to queries using the
+	// query state snapshot currently held by the provided pointer. Snapshots must not be modified.
+	SnapshotQueryResponderFactory func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error)
+
+	// stateQueryResponder adapts a responder created by a QueryResponderFactory to the query state snapshots of
+	// the server. Before each query, the responder is given a copy of the current snapshot if it implements
+	// stateSetter, otherwise the query state given to the factory is updated in place. Queries are answered one at
+	// a time, so the state is never replaced while the responder is reading it.
+	stateQueryResponder struct {
+		snapshots *atomic.Pointer[proto.QueryState]
+
+		mtx       sync.Mutex
+		state     *proto.QueryState
+		snapshot  *proto.QueryState
+		responder proto.QueryResponder
+	}
+
+	// queryProtocolResponder holds the responder of a query protocol, along with the name of the protocol.
+	queryProtocolResponder struct {
+		name      QueryProtocol
+		responder proto.QueryResponder
+	}
+
+	// stateSetter is implemented by responders whose query state can be replaced, such as those embedding
+	// proto.QueryBase.
+	stateSetter interface {
+		SetState(state *proto.QueryState)
+	}
 )
 
 const (
@@ -28,72 +71,301 @@ const (
 
 	// QueryProtocolRecommended represents the recommended query protocol.
 	QueryProtocolRecommended
+
+	// QueryProtocolQ3 represents the Quake 3 (ioquake3) 'getstatus' and 'getinfo' query protocol.
+	QueryProtocolQ3 = QueryProtocol("q3")
+
+	// QueryProtocolGameSpy4 represents the GameSpy4 query protocol, as used by Unreal Tournament 3 and the Minecraft
+	// UDP query.
+	QueryProtocolGameSpy4 = QueryProtocol("gamespy4")
+
+	// QueryProtocolSQPAndA2S represents serving both the 'sqp' and 'a2s' query protocols on the same port. The
+	// protocol of each request is detected from its header.
+	QueryProtocolSQPAndA2S = QueryProtocol("sqp+a2s")
 )
 
-// ErrUnsupportedQueryType is an error that specifies the provided query type is not supported by this library.
-var ErrUnsupportedQueryType = errors.New("supplied query type is not supported")
+const (
+	// QueryBindIPv4 represents binding the query endpoint on IPv4 only. This is the default.
+	QueryBindIPv4 = QueryBindMode(iota)
 
-// switchQueryProtocol switches to a query protocol specified in the configuration.
-// The query binding endpoints are restarted to serve on this endpoint.
-func (s *Server) switchQueryProtocol(c Config) error {
-	var err error
-	switch c.QueryType {
-	case QueryProtocolA2S:
-		s.queryProto, err = a2s.NewQueryResponder(&s.state)
-	case QueryProtocolSQP:
-		s.queryProto, err = sqp.NewQueryResponder(&s.state)
-	default:
-		return ErrUnsupportedQueryType
+	// QueryBindIPv6 represents binding the query endpoint on IPv6 only.
+	QueryBindIPv6
+
+	// QueryBindDualStack represents binding the query endpoint on both IPv4 and IPv6.
+	QueryBindDualStack
+)
+
+var (
+	// ErrUnsupportedQueryType is an error that specifies the provided query type is not supported by this library.
+	ErrUnsupportedQueryType = errors.New("supplied query type is not supported")
+
+	// ErrQueryProtocolRegistered is an error that specifies a query protocol has already been registered with the
+	// provided name.
+	ErrQueryProtocolRegistered = errors.New("query protocol is already registered")
+
+	// ErrInvalidQueryProtocol is an error that specifies the query protocol supplied for registration is invalid.
+	ErrInvalidQueryProtocol = errors.New("query protocol name or factory is invalid")
+
+	// ErrInvalidQueryBind is an error that specifies the query endpoint cannot be bound with the configured bind mode.
+	ErrInvalidQueryBind = errors.New("invalid query bind")
+
+	// ErrReusePortUnsupported is an error that specifies SO_REUSEPORT is not supported on the current platform.
+	ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported on this platform")
+)
+
+var (
+	// queryProtocols holds the factories for every supported query protocol, keyed by name.
+	queryProtocols = map[QueryProtocol]SnapshotQueryResponderFactory{
+		QueryProtocolA2S: func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+			return a2s.NewQueryResponderFromSnapshot(state)
+		},
+		QueryProtocolSQP: func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+			return sqp.NewQueryResponderFromSnapshot(state)
+		},
+		QueryProtocolQ3: func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+			return q3.NewQueryResponderFromSnapshot(state)
+		},
+		QueryProtocolGameSpy4: func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+			return gs4.NewQueryResponderFromSnapshot(state)
+		},
+		QueryProtocolSQPAndA2S: func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+			return multi.NewQueryResponderFromSnapshot(state)
+		},
+	}
+	queryProtocolsMtx sync.RWMutex
+)
+
+// RegisterQueryProtocol registers a query protocol with the provided name, allowing it to be selected with the
+// `queryType` field of the server configuration. Custom protocols must be registered before the server is started.
+// ErrQueryProtocolRegistered is returned if a protocol with the same name already exists, including the built-in
+// protocols. The query state given to the responder is kept up to date, but queries are answered one at a time;
+// use RegisterSnapshotQueryProtocol to answer queries concurrently.
+func RegisterQueryProtocol(name QueryProtocol, factory QueryResponderFactory) error {
+	if factory == nil {
+		return ErrInvalidQueryProtocol
+	}
+
+	return RegisterSnapshotQueryProtocol(name, func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+		return newStateQueryResponder(factory, state)
+	})
+}
+
+// RegisterSnapshotQueryProtocol registers a query protocol as per RegisterQueryProtocol, whose responder reads the
+// current query state snapshot for each query.
+func RegisterSnapshotQueryProtocol(name QueryProtocol, factory SnapshotQueryResponderFactory) error {
+	if name == "" || factory == nil {
+		return ErrInvalidQueryProtocol
+	}
+
+	queryProtocolsMtx.Lock()
+	defer queryProtocolsMtx.Unlock()
+
+	if _, ok := queryProtocols[name]; ok {
+		return fmt.Errorf("%w: %s", ErrQueryProtocolRegistered, name)
+	}
+
+	queryProtocols[name] = factory
+	return nil
+}
+
+// newStateQueryResponder creates a responder using factory, which is given a query state kept up to date with the
+// provided snapshots.
+func newStateQueryResponder(
+	factory QueryResponderFactory,
+	snapshots *atomic.Pointer[proto.QueryState],
+) (*stateQueryResponder, error) {
+	r := &stateQueryResponder{
+		snapshots: snapshots,
+		state:     &proto.QueryState{},
 	}
 
+	r.refresh()
+
+	responder, err := factory(r.state)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return s.restartQueryEndpoint(c)
+	r.responder = responder
+	return r, nil
 }
 
-// restartQueryEndpoint restarts the query endpoint to support a potential change of query protocol in the
-// configuration.
-func (s *Server) restartQueryEndpoint(c Config) error {
-	if s.queryBind != nil {
-		s.queryBind.Close()
-		s.queryBind = nil
+// Respond responds to a query using the current query state.
+func (r *stateQueryResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.refresh()
+	return r.responder.Respond(clientAddress, buf)
+}
+
+// ChallengeSatisfied determines whether the client has satisfied a challenge, if the responder supports challenges.
+func (r *stateQueryResponder) ChallengeSatisfied(clientAddress string) bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	return challengeSatisfied(r.responder, clientAddress)
+}
+
+// refresh gives the responder a copy of the current snapshot, if it has changed. A fresh copy is given to responders
+// which implement stateSetter, so that responses they cache for a query state are not reused for the next one. The
+// caller must hold mtx, unless the responder has not been created.
+func (r *stateQueryResponder) refresh() {
+	qs := r.snapshots.Load()
+	if qs == nil || qs == r.snapshot {
+		return
 	}
 
-	var err error
-	s.queryBind, err = newUDPBinding(
-		fmt.Sprintf(":%s", c.QueryPort),
-		s.queryReadBufferSizeBytes,
-		s.queryWriteBufferSizeBytes,
-		s.queryReadDeadlineDuration,
-		s.queryWriteDeadlineDuration,
-	)
+	r.snapshot = qs
+	state := *qs
+
+	if setter, ok := r.responder.(stateSetter); ok {
+		setter.SetState(&state)
+		return
+	}
+
+	*r.state = state
+}
 
+// supportsMetrics determines whether the named query protocol supports additional metrics.
+func supportsMetrics(name QueryProtocol) bool {
+	return name == QueryProtocolSQP || name == QueryProtocolSQPAndA2S
+}
+
+// newQueryResponder creates a new responder for the named query protocol.
+func newQueryResponder(name QueryProtocol, state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+	queryProtocolsMtx.RLock()
+	factory, ok := queryProtocols[name]
+	queryProtocolsMtx.RUnlock()
+
+	if !ok {
+		return nil, ErrUnsupportedQueryType
+	}
+
+	return factory(state)
+}
+
+// switchQueryProtocol switches to the query protocol and endpoint specified in the configuration. A new endpoint is
+// bound before the current one is retired, so that the current endpoint continues to respond to queries if the new
+// one cannot be bound. The responder is only replaced if the query protocol has changed, so that any challenges it
+// has issued remain valid. The caller must hold queryMtx.
+func (s *Server) switchQueryProtocol(c Config) error {
+	network, address, err := s.queryBindAddress(c)
+	if err != nil {
+		return err
+	}
+
+	current := s.queryEndpoint
+
+	qp := s.queryProto.Load()
+	if current == nil || qp == nil || c.QueryType != qp.name {
+		responder, err := s.newQueryResponder(c.QueryType)
+		if err != nil {
+			return err
+		}
+
+		qp = &queryProtocolResponder{name: c.QueryType, responder: responder}
+	}
+
+	if current != nil && current.network == network && current.address == address {
+		s.queryProto.Store(qp)
+		return nil
+	}
+
+	e, err := s.openQueryEndpoint(network, address)
 	if err != nil {
 		return err
 	}
 
-	go s.handleQuery()
+	s.queryProto.Store(qp)
+	s.queryEndpoint = e
+	s.startQueryWorkers(e)
+
+	if current != nil {
+		s.retireQueryEndpoint(current)
+	}
+
 	return nil
 }
 
-// handleQuery handles responding to query commands on an incoming UDP port.
-func (s *Server) handleQuery() {
-	buf := make([]byte, s.queryReadBufferSizeBytes)
+// newQueryResponder creates a new responder for the named query protocol, enabling stateless challenges if
+// configured.
+func (s *Server) newQueryResponder(name QueryProtocol) (proto.QueryResponder, error) {
+	qp, err := newQueryResponder(name, &s.state)
+	if err != nil {
+		return nil, err
+	}
 
-	s.wg.Add(1)
-	defer s.wg.Done()
+	if sc, ok := qp.(proto.StatelessChallengeResponder); ok && s.queryStatelessChallenges {
+		if err = sc.EnableStatelessChallenges(); err != nil {
+			return nil, fmt.Errorf("error enabling stateless challenges: %w", err)
+		}
+	}
 
-	for {
-		// Clear buffer before use
-		for i := range buf {
-			buf[i] = 0
+	return qp, nil
+}
+
+// queryResponder returns the responder for the current query protocol.
+func (s *Server) queryResponder() *queryProtocolResponder {
+	return s.queryProto.Load()
+}
+
+// closeQueryEndpoint closes all sockets of the query endpoint, which stops the query workers reading from them.
+// The caller must hold queryMtx.
+func (s *Server) closeQueryEndpoint() {
+	if s.queryEndpoint != nil {
+		s.queryEndpoint.close()
+	}
+
+	s.queryEndpoint = nil
+}
+
+// queryBindAddress determines the network and address to bind the query endpoint to, based upon the configured bind
+// mode.
+func (s *Server) queryBindAddress(c Config) (string, string, error) {
+	var network, host string
+
+	switch s.queryBindMode {
+	case QueryBindIPv6:
+		network = "udp6"
+		if s.queryBindConfiguredAddress {
+			host = c.IPv6
+		}
+
+	case QueryBindDualStack:
+		network = "udp"
+		if s.queryBindConfiguredAddress {
+			return "", "", fmt.Errorf("%w: a dual-stack endpoint cannot be bound to a single address", ErrInvalidQueryBind)
 		}
 
-		_, to, err := s.queryBind.Read(buf)
+	default:
+		network = "udp4"
+		if s.queryBindConfiguredAddress {
+			host = c.IP
+		}
+	}
+
+	if s.queryBindConfiguredAddress && host == "" {
+		return "", "", fmt.Errorf("%w: no address configured for %s", ErrInvalidQueryBind, network)
+	}
+
+	return network, net.JoinHostPort(host, c.QueryPort.String()), nil
+}
+
+// handleQuery handles responding to query commands on an incoming UDP port of the query endpoint, until the endpoint
+// is closed or retired. The caller must add to the wait groups of the server and endpoint before starting the
+// handler.
+func (s *Server) handleQuery(e *queryEndpoint, b *udpBinding) {
+	defer s.wg.Done()
+	defer e.workers.Done()
+
+	// Only the first n bytes of the buffer are passed on, so there is no need to clear it between reads.
+	buf := make([]byte, s.queryReadBufferSizeBytes)
+
+	for !e.isRetired() {
+		n, to, err := b.Read(buf)
 		if err != nil {
-			if s.queryBind.IsDone() {
+			if b.IsDone() || e.isRetired() {
 				return
 			}
 
@@ -109,19 +381,113 @@ func (s *Server) handleQuery() {
 			continue
 		}
 
-		resp, err := s.queryProto.Respond(to.String(), buf)
-		if err != nil {
-			s.PushError(fmt.Errorf("query: error responding: %w", err))
+		if !s.queryLimiter.allowRequest(to.IP, time.Now()) {
 			continue
 		}
 
-		if _, err = s.queryBind.Write(resp, to); err != nil {
-			if s.queryBind.IsDone() {
-				return
+		// The responder is loaded for each request, as it is replaced if the query protocol changes.
+		if !s.writeQueryResponse(b, s.queryResponder(), to, buf[:n]) {
+			return
+		}
+	}
+}
+
+// writeQueryResponse responds to a single query request, returning false if the query endpoint has been closed.
+func (s *Server) writeQueryResponse(b *udpBinding, qp *queryProtocolResponder, to *net.UDPAddr, buf []byte) bool {
+	dst := proto.GetBuffer()
+	defer proto.PutBuffer(dst)
+
+	clientAddress := to.String()
+
+	packets, err := s.respondToQuery(qp.responder, dst, clientAddress, buf)
+	if err != nil {
+		s.PushError(fmt.Errorf("query: error responding: %w", err))
+		return true
+	}
+
+	satisfied := challengeSatisfied(qp.responder, clientAddress)
+	if !s.queryLimiter.allowResponse(qp.name, len(buf), packets, satisfied, time.Now()) {
+		return true
+	}
+
+	for _, resp := range packets {
+		if _, err = b.Write(resp, to); err != nil {
+			if b.IsDone() {
+				return false
 			}
 
 			s.PushError(fmt.Errorf("query: error writing to socket: %w", err))
-			continue
+			break
 		}
 	}
+
+	return true
+}
+
+// challengeSatisfied determines whether the client has satisfied a challenge of the query protocol. Protocols which
+// do not implement proto.ChallengeVerifier are never satisfied.
+func challengeSatisfied(qp proto.QueryResponder, clientAddress string) bool {
+	if cv, ok := qp.(proto.ChallengeVerifier); ok {
+		return cv.ChallengeSatisfied(clientAddress)
+	}
+
+	return false
+}
+
+// respondToQuery generates the packets to respond to a query with, using the provided responder. Responses are
+// appended to dst where supported, in which case any growth of dst is retained for subsequent responses. If the query
+// protocol supports it, responses larger than the write buffer are split across multiple packets.
+func (s *Server) respondToQuery(qp proto.QueryResponder, dst *[]byte, clientAddress string, buf []byte) ([][]byte, error) {
+	if ar, ok := qp.(proto.AppendResponder); ok {
+		resp, err := ar.AppendResponse((*dst)[:0], clientAddress, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		// The response was appended to dst, so any growth is retained. Responses from responders which do not append
+		// to dst may be owned by the responder, so are never retained.
+		if cap(resp) > cap(*dst) {
+			*dst = resp[:0]
+		}
+
+		if rs, ok := qp.(proto.ResponseSplitter); ok {
+			return rs.SplitResponse(resp, s.queryWriteBufferSizeBytes)
+		}
+
+		return [][]byte{resp}, nil
+	}
+
+	if mp, ok := qp.(proto.MultiPacketResponder); ok {
+		return mp.RespondPackets(clientAddress, buf, s.queryWriteBufferSizeBytes)
+	}
+
+	resp, err := qp.Respond(clientAddress, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{resp}, nil
+}
+
+// applyQueryConfig switches the query protocol and endpoint in response to a change of the query type, query port or
+// addresses in the configuration. Nothing is done if the query endpoint is not open, for example if the server has
+// been stopped, or if the new configuration has no query port, as is the case for the minimal configuration written
+// on deallocation.
+func (s *Server) applyQueryConfig(change ConfigChange) error {
+	if !change.Changed("queryType") && !change.Changed("queryPort") && !change.Changed("ip") && !change.Changed("ipv6") {
+		return nil
+	}
+
+	if change.New.QueryPort == "" {
+		return nil
+	}
+
+	s.queryMtx.Lock()
+	defer s.queryMtx.Unlock()
+
+	if s.queryEndpoint == nil {
+		return nil
+	}
+
+	return s.switchQueryProtocol(change.New)
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"sync"
+)
+
+type (
+	// queryEndpoint represents the sockets bound to a single query address, along with the workers reading from them.
+	queryEndpoint struct {
+		network string
+		address string
+		binds   []*udpBinding
+
+		// retired is closed when the endpoint is replaced, after which its workers stop once they have responded to
+		// any request they have read.
+		retired chan struct{}
+
+		// workers tracks the workers reading from the endpoint.
+		workers sync.WaitGroup
+	}
+)
+
+// openQueryEndpoint binds the sockets of a query endpoint to the provided network and address. If port reuse is
+// enabled, each query worker reads from its own socket, otherwise all workers share a single socket.
+func (s *Server) openQueryEndpoint(network string, address string) (*queryEndpoint, error) {
+	sockets := 1
+	if s.queryReusePort {
+		sockets = s.queryWorkers
+	}
+
+	e := &queryEndpoint{
+		network: network,
+		address: address,
+		binds:   make([]*udpBinding, 0, sockets),
+		retired: make(chan struct{}),
+	}
+
+	for i := 0; i < sockets; i++ {
+		b, err := newUDPBinding(
+			network,
+			address,
+			s.queryReusePort,
+			s.queryReadBufferSizeBytes,
+			s.queryWriteBufferSizeBytes,
+			s.queryReadDeadlineDuration,
+			s.queryWriteDeadlineDuration,
+		)
+		if err != nil {
+			e.close()
+			return nil, err
+		}
+
+		e.binds = append(e.binds, b)
+	}
+
+	return e, nil
+}
+
+// startQueryWorkers starts the configured number of query workers reading from the endpoint.
+func (s *Server) startQueryWorkers(e *queryEndpoint) {
+	for i := 0; i < s.queryWorkers; i++ {
+		s.wg.Add(1)
+		e.workers.Add(1)
+		go s.handleQuery(e, e.binds[i%len(e.binds)])
+	}
+}
+
+// retireQueryEndpoint stops the workers of an endpoint which has been replaced, closing its sockets once they have
+// stopped, or once the server is stopped.
+func (s *Server) retireQueryEndpoint(e *queryEndpoint) {
+	close(e.retired)
+	for _, b := range e.binds {
+		b.Interrupt()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		e.workers.Wait()
+		close(stopped)
+	}()
+
+	go func() {
+		select {
+		case <-stopped:
+		case <-s.done:
+		}
+
+		e.close()
+	}()
+}
+
+// isRetired determines whether the endpoint has been replaced.
+func (e *queryEndpoint) isRetired() bool {
+	select {
+	case <-e.retired:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes all sockets of the endpoint, which stops the workers reading from them.
+func (e *queryEndpoint) close() {
+	for _, b := range e.binds {
+		b.Close()
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// switchQuery switches the query protocol and endpoint of the server to those in the provided configuration.
+func switchQuery(s *Server, queryType QueryProtocol, endpoint string) error {
+	s.queryMtx.Lock()
+	defer s.queryMtx.Unlock()
+
+	return s.switchQueryProtocol(Config{
+		QueryPort: json.Number(strings.Split(endpoint, ":")[1]),
+		QueryType: queryType,
+	})
+}
+
+// exchange sends a request from conn to the endpoint, returning the response.
+func exchange(t *testing.T, conn *net.UDPConn, endpoint string, req []byte) []byte {
+	t.Helper()
+
+	addr, err := net.ResolveUDPAddr("udp4", endpoint)
+	require.NoError(t, err)
+
+	_, err = conn.WriteToUDP(req, addr)
+	require.NoError(t, err)
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFromUDP(buf)
+	require.NoError(t, err)
+
+	return buf[:n]
+}
+
+func Test_switchQueryProtocol_samePortKeepsSockets(t *testing.T) {
+	t.Parallel()
+
+	endpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+	require.NoError(t, switchQuery(s, QueryProtocolSQP, endpoint))
+	e := s.queryEndpoint
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Only the responder is replaced, as the endpoint cannot be bound twice without port reuse.
+	require.NoError(t, switchQuery(s, QueryProtocolA2S, endpoint))
+	require.Same(t, e, s.queryEndpoint)
+	require.False(t, e.isRetired())
+
+	resp := exchange(t, conn, endpoint, []byte("\xFF\xFF\xFF\xFFTSource Engine Query\x00"))
+	require.Equal(t, byte(0x41), resp[4])
+
+	require.NoError(t, s.Stop())
+}
+
+func Test_switchQueryProtocol_newPortKeepsChallenges(t *testing.T) {
+	t.Parallel()
+
+	endpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	newEndpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+	require.NoError(t, switchQuery(s, QueryProtocolSQP, endpoint))
+	e := s.queryEndpoint
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp := exchange(t, conn, endpoint, []byte{0, 0, 0, 0, 0})
+	require.Equal(t, byte(0), resp[0])
+	challenge := binary.BigEndian.Uint32(resp[1:5])
+
+	require.NoError(t, switchQuery(s, QueryProtocolSQP, newEndpoint))
+	require.NotSame(t, e, s.queryEndpoint)
+	require.True(t, e.isRetired())
+
+	// The challenge issued before the switch is answered on the new endpoint.
+	req := []byte{1, 0, 0, 0, 0, 0, 2, 1}
+	binary.BigEndian.PutUint32(req[1:5], challenge)
+	resp = exchange(t, conn, newEndpoint, req)
+	require.Equal(t, byte(1), resp[0])
+	require.Equal(t, challenge, binary.BigEndian.Uint32(resp[1:5]))
+
+	// The old endpoint is closed once its workers have stopped.
+	require.Eventually(t, func() bool {
+		l, err := net.ListenPacket("udp4", endpoint)
+		if err != nil {
+			return false
+		}
+
+		return l.Close() == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, s.Stop())
+}
+
+func Test_switchQueryProtocol_bindFailureKeepsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	endpoint, err := getRandomPortAssignment()
+	require.NoError(t, err)
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+	require.NoError(t, switchQuery(s, QueryProtocolSQP, endpoint))
+	e := s.queryEndpoint
+
+	occupied, err := net.ListenPacket("udp4", ":0")
+	require.NoError(t, err)
+	defer occupied.Close()
+
+	require.Error(t, switchQuery(s, QueryProtocolA2S, occupied.LocalAddr().String()))
+	require.Same(t, e, s.queryEndpoint)
+	require.Equal(t, QueryProtocolSQP, s.queryResponder().name)
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resp := exchange(t, conn, endpoint, []byte{0, 0, 0, 0, 0})
+	require.Equal(t, byte(0), resp[0])
+
+	require.NoError(t, s.Stop())
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/a2s"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/gs4"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/multi"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/q3"
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+type testQueryResponder struct {
+	state *proto.QueryState
+}
+
+func (r *testQueryResponder) Respond(_ string, _ []byte) ([]byte, error) {
+	return []byte(r.state.ServerName), nil
+}
+
+type testSnapshotQueryResponder struct {
+	state *atomic.Pointer[proto.QueryState]
+}
+
+func (r *testSnapshotQueryResponder) Respond(_ string, _ []byte) ([]byte, error) {
+	return []byte(r.state.Load().ServerName), nil
+}
+
+// unregisterQueryProtocol removes the named query protocol once the test completes, so that tests registering
+// protocols can be run more than once.
+func unregisterQueryProtocol(t *testing.T, name QueryProtocol) {
+	t.Cleanup(func() {
+		queryProtocolsMtx.Lock()
+		defer queryProtocolsMtx.Unlock()
+		delete(queryProtocols, name)
+	})
+}
+
+func Test_RegisterQueryProtocol(t *testing.T) {
+	t.Parallel()
+
+	const name = QueryProtocol("test-register")
+	unregisterQueryProtocol(t, name)
+	factory := func(state *proto.QueryState) (proto.QueryResponder, error) {
+		return &testQueryResponder{state: state}, nil
+	}
+
+	require.NoError(t, RegisterQueryProtocol(name, factory))
+	require.ErrorIs(t, RegisterQueryProtocol(name, factory), ErrQueryProtocolRegistered)
+	require.ErrorIs(t, RegisterQueryProtocol(QueryProtocolSQP, factory), ErrQueryProtocolRegistered)
+	require.ErrorIs(t, RegisterQueryProtocol("", factory), ErrInvalidQueryProtocol)
+	require.ErrorIs(t, RegisterQueryProtocol("test-register-nil", nil), ErrInvalidQueryProtocol)
+
+	state := proto.NewQueryStatePointer(&proto.QueryState{ServerName: "foo"})
+	r, err := newQueryResponder(name, state)
+	require.NoError(t, err)
+
+	resp, err := r.Respond("client-addr:1234", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), resp)
+
+	// The state given to the responder follows the current snapshot.
+	state.Store(&proto.QueryState{ServerName: "bar", Revision: 1})
+	resp, err = r.Respond("client-addr:1234", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("bar"), resp)
+}
+
+func Test_RegisterQueryProtocol_sqp(t *testing.T) {
+	t.Parallel()
+
+	const name = QueryProtocol("test-register-sqp")
+	unregisterQueryProtocol(t, name)
+	require.NoError(t, RegisterQueryProtocol(name, func(state *proto.QueryState) (proto.QueryResponder, error) {
+		return sqp.NewQueryResponder(state)
+	}))
+
+	state := proto.NewQueryStatePointer(&proto.QueryState{Metrics: []float32{1.234}})
+	r, err := newQueryResponder(name, state)
+	require.NoError(t, err)
+
+	// queryMetrics requests the metrics chunk, returning the encoding of the first metric.
+	queryMetrics := func() []byte {
+		challenge, err := r.Respond("client-addr:1234", []byte{0, 0, 0, 0, 0})
+		require.NoError(t, err)
+
+		resp, err := r.Respond("client-addr:1234", bytes.Join([][]byte{{1}, challenge[1:5], {0, 2}, {0b00010000}}, nil))
+		require.NoError(t, err)
+		return resp[len(resp)-4:]
+	}
+
+	require.Equal(t, binary.BigEndian.AppendUint32(nil, math.Float32bits(1.234)), queryMetrics())
+
+	// Metric updates are seen by the next query.
+	state.Store(&proto.QueryState{Metrics: []float32{5.678}})
+	require.Equal(t, binary.BigEndian.AppendUint32(nil, math.Float32bits(5.678)), queryMetrics())
+}
+
+func Test_RegisterSnapshotQueryProtocol(t *testing.T) {
+	t.Parallel()
+
+	const name = QueryProtocol("test-register-snapshot")
+	unregisterQueryProtocol(t, name)
+	factory := func(state *atomic.Pointer[proto.QueryState]) (proto.QueryResponder, error) {
+		return &testSnapshotQueryResponder{state: state}, nil
+	}
+
+	require.NoError(t, RegisterSnapshotQueryProtocol(name, factory))
+	require.ErrorIs(t, RegisterSnapshotQueryProtocol(name, factory), ErrQueryProtocolRegistered)
+	require.ErrorIs(t, RegisterSnapshotQueryProtocol("", factory), ErrInvalidQueryProtocol)
+
+	state := proto.NewQueryStatePointer(&proto.QueryState{ServerName: "foo"})
+	r, err := newQueryResponder(name, state)
+	require.NoError(t, err)
+	require.IsType(t, &testSnapshotQueryResponder{}, r)
+
+	resp, err := r.Respond("client-addr:1234", nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), resp)
+}
+
+func Test_newQueryResponder(t *testing.T) {
+	t.Parallel()
+
+	r, err := newQueryResponder(QueryProtocolSQP, proto.NewQueryStatePointer(&proto.QueryState{}))
+	require.NoError(t, err)
+	require.IsType(t, &sqp.QueryResponder{}, r)
+
+	r, err = newQueryResponder(QueryProtocolA2S, proto.NewQueryStatePointer(&proto.QueryState{}))
+	require.NoError(t, err)
+	require.IsType(t, &a2s.QueryResponder{}, r)
+
+	r, err = newQueryResponder(QueryProtocolSQPAndA2S, proto.NewQueryStatePointer(&proto.QueryState{}))
+	require.NoError(t, err)
+	require.IsType(t, &multi.QueryResponder{}, r)
+
+	r, err = newQueryResponder(QueryProtocolQ3, proto.NewQueryStatePointer(&proto.QueryState{}))
+	require.NoError(t, err)
+	require.IsType(t, &q3.QueryResponder{}, r)
+
+	r, err = newQueryResponder(QueryProtocolGameSpy4, proto.NewQueryStatePointer(&proto.QueryState{}))
+	require.NoError(t, err)
+	require.IsType(t, &gs4.QueryResponder{}, r)
+
+	_, err = newQueryResponder("unknown", proto.NewQueryStatePointer(&proto.QueryState{}))
+	require.ErrorIs(t, err, ErrUnsupportedQueryType)
+}
+
+func Test_supportsMetrics(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, supportsMetrics(QueryProtocolSQP))
+	require.True(t, supportsMetrics(QueryProtocolSQPAndA2S))
+	require.False(t, supportsMetrics(QueryProtocolA2S))
+}
+
+func Test_respondToQuery(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation, WithQueryWriteBuffer(100))
+	require.NoError(t, err)
+
+	rules := map[string]any{}
+	for i := 0; i < 20; i++ {
+		rules[fmt.Sprintf("rule-%d", i)] = i
+	}
+
+	require.NoError(t, s.SetRules(rules))
+
+	r, err := newQueryResponder(QueryProtocolA2S, &s.state)
+	require.NoError(t, err)
+
+	const clientAddr = "client-addr:1234"
+	dst := make([]byte, 0, 1024)
+
+	// Responses which fit into a single packet are appended to dst.
+	packets, err := s.respondToQuery(r, &dst, clientAddr, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x56, 0xFF, 0xFF, 0xFF, 0xFF})
+	require.NoError(t, err)
+	require.Len(t, packets, 1)
+	require.Same(t, &dst[:1][0], &packets[0][0])
+
+	// Responses larger than the write buffer are split.
+	req := append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x56}, packets[0][5:9]...)
+	packets, err = s.respondToQuery(r, &dst, clientAddr, req)
+	require.NoError(t, err)
+	require.Greater(t, len(packets), 1)
+
+	for _, p := range packets {
+		require.LessOrEqual(t, len(p), 100)
+		require.Equal(t, []byte{0xFE, 0xFF, 0xFF, 0xFF}, p[:4])
+	}
+
+	// Growth of dst is retained when the response was appended to it.
+	small := make([]byte, 0, 4)
+	packets, err = s.respondToQuery(r, &small, clientAddr, []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x56, 0xFF, 0xFF, 0xFF, 0xFF})
+	require.NoError(t, err)
+	require.Len(t, packets, 1)
+	require.Same(t, &small[:1][0], &packets[0][0])
+
+	// Responses owned by responders which do not append to dst are never retained.
+	owned := &testQueryResponder{state: &proto.QueryState{ServerName: "a response longer than dst"}}
+	small = make([]byte, 0, 4)
+	packets, err = s.respondToQuery(owned, &small, clientAddr, nil)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("a response longer than dst")}, packets)
+	require.Equal(t, 4, cap(small))
+}
+
+func Test_queryBindAddress(t *testing.T) {
+	t.Parallel()
+
+	c := Config{IP: "10.0.0.1", IPv6: "2001:db8::1", QueryPort: "9010"}
+
+	tests := []struct {
+		name              string
+		mode              QueryBindMode
+		configured        bool
+		config            Config
+		expectedNetwork   string
+		expectedAddress   string
+		expectedErrorType error
+	}{
+		{name: "ipv4", mode: QueryBindIPv4, config: c, expectedNetwork: "udp4", expectedAddress: ":9010"},
+		{name: "ipv6", mode: QueryBindIPv6, config: c, expectedNetwork: "udp6", expectedAddress: ":9010"},
+		{name: "dual-stack", mode: QueryBindDualStack, config: c, expectedNetwork: "udp", expectedAddress: ":9010"},
+		{name: "configured ipv4", mode: QueryBindIPv4, configured: true, config: c, expectedNetwork: "udp4", expectedAddress: "10.0.0.1:9010"},
+		{name: "configured ipv6", mode: QueryBindIPv6, configured: true, config: c, expectedNetwork: "udp6", expectedAddress: "[2001:db8::1]:9010"},
+		{name: "configured dual-stack", mode: QueryBindDualStack, configured: true, config: c, expectedErrorType: ErrInvalidQueryBind},
+		{name: "configured ipv6 missing", mode: QueryBindIPv6, configured: true, config: Config{QueryPort: "9010"}, expectedErrorType: ErrInvalidQueryBind},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &Server{queryBindMode: tt.mode, queryBindConfiguredAddress: tt.configured}
+			network, address, err := s.queryBindAddress(tt.config)
+			require.ErrorIs(t, err, tt.expectedErrorType)
+			require.Equal(t, tt.expectedNetwork, network)
+			require.Equal(t, tt.expectedAddress, address)
+		})
+	}
+}
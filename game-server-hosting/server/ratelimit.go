@@ -0,0 +1,300 @@
+package server
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// QueryLimitStats holds the number of query requests which have not been answered due to query limits.
+	QueryLimitStats struct {
+		// Limited is the number of requests which were not answered because a rate limit was exceeded.
+		Limited uint64
+
+		// Dropped is the number of responses which were not sent because they exceeded the maximum
+		// response-to-request size ratio before the client satisfied a challenge.
+		Dropped uint64
+	}
+
+	// rateLimit configures a single token bucket rate limit. A zero rate disables the limit.
+	rateLimit struct {
+		rate  float64
+		burst int
+	}
+
+	// queryLimits holds the configuration of the query handler limits.
+	queryLimits struct {
+		perIP                 rateLimit
+		perSubnet             rateLimit
+		subnetPrefixIPv4      int
+		subnetPrefixIPv6      int
+		global                rateLimit
+		maxAmplificationRatio float64
+
+		// amplificationExempt holds the query protocols whose responses are exempt from maxAmplificationRatio.
+		amplificationExempt map[QueryProtocol]bool
+	}
+
+	// tokenBucket is a token bucket which refills at a fixed rate up to a maximum burst size.
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	// sourceBuckets holds the token buckets of a per-source rate limit. Once the maximum number of sources is
+	// tracked, the least recently used source is evicted to track a new one.
+	sourceBuckets struct {
+		limit      rateLimit
+		maxTracked int
+		buckets    map[string]*list.Element
+
+		// lru holds the *sourceBucket of each tracked source, most recently used first.
+		lru *list.List
+	}
+
+	// sourceBucket is the token bucket of a single source.
+	sourceBucket struct {
+		key    string
+		bucket tokenBucket
+	}
+
+	// queryLimiter applies rate limits and amplification protection to the query handler.
+	queryLimiter struct {
+		limits queryLimits
+
+		mtx         sync.Mutex
+		perIP       *sourceBuckets
+		perSubnet   *sourceBuckets
+		global      tokenBucket
+		lastPurged  time.Time
+		purgePeriod time.Duration
+
+		limited uint64
+		dropped uint64
+	}
+)
+
+const (
+	// DefaultQuerySubnetPrefixIPv4 is the default prefix length used to group IPv4 addresses into subnets for
+	// per-subnet query rate limits.
+	DefaultQuerySubnetPrefixIPv4 = 24
+
+	// DefaultQuerySubnetPrefixIPv6 is the default prefix length used to group IPv6 addresses into subnets for
+	// per-subnet query rate limits.
+	DefaultQuerySubnetPrefixIPv6 = 64
+
+	// maxTrackedQuerySources is the maximum number of sources tracked by each per-source rate limit. Once reached,
+	// the least recently used source is evicted to track a new one.
+	maxTrackedQuerySources = 65536
+
+	// queryLimiterPurgePeriod is how often idle sources are purged from the per-source rate limits.
+	queryLimiterPurgePeriod = 10 * time.Second
+)
+
+// newQueryLimiter creates a new query limiter with the provided limits.
+func newQueryLimiter(limits queryLimits) *queryLimiter {
+	if limits.subnetPrefixIPv4 <= 0 {
+		limits.subnetPrefixIPv4 = DefaultQuerySubnetPrefixIPv4
+	}
+
+	if limits.subnetPrefixIPv6 <= 0 {
+		limits.subnetPrefixIPv6 = DefaultQuerySubnetPrefixIPv6
+	}
+
+	return &queryLimiter{
+		limits:      limits,
+		perIP:       newSourceBuckets(limits.perIP),
+		perSubnet:   newSourceBuckets(limits.perSubnet),
+		global:      tokenBucket{tokens: float64(limits.global.burst)},
+		purgePeriod: queryLimiterPurgePeriod,
+	}
+}
+
+// newSourceBuckets creates the token buckets of a per-source rate limit.
+func newSourceBuckets(limit rateLimit) *sourceBuckets {
+	return &sourceBuckets{
+		limit:      limit,
+		maxTracked: maxTrackedQuerySources,
+		buckets:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// allowRequest determines whether a request from the provided address is within the per-source rate limits.
+func (l *queryLimiter) allowRequest(ip net.IP, now time.Time) bool {
+	if l.limits.perIP.rate <= 0 && l.limits.perSubnet.rate <= 0 {
+		return true
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if now.Sub(l.lastPurged) > l.purgePeriod {
+		l.purge(now)
+	}
+
+	var ipBucket, subnetBucket *tokenBucket
+	if l.limits.perIP.rate > 0 {
+		ipBucket = l.perIP.get(ip.String(), now)
+	}
+
+	if l.limits.perSubnet.rate > 0 {
+		subnetBucket = l.perSubnet.get(l.subnet(ip), now)
+	}
+
+	// A token is only taken if both limits allow the request, so that requests refused by one limit do not count
+	// against the other.
+	if !ipBucket.available(l.limits.perIP, 1, now) || !subnetBucket.available(l.limits.perSubnet, 1, now) {
+		atomic.AddUint64(&l.limited, 1)
+		return false
+	}
+
+	ipBucket.take(l.limits.perIP, 1, now)
+	subnetBucket.take(l.limits.perSubnet, 1, now)
+
+	return true
+}
+
+// allowResponse determines whether a response of the provided packets to a request of requestSize bytes is within the
+// global rate limit and, if the client has not satisfied a challenge and the query protocol is not exempt, the maximum
+// amplification ratio.
+func (l *queryLimiter) allowResponse(
+	protocol QueryProtocol,
+	requestSize int,
+	packets [][]byte,
+	challengeSatisfied bool,
+	now time.Time,
+) bool {
+	if l.limits.maxAmplificationRatio > 0 && !challengeSatisfied && !l.limits.amplificationExempt[protocol] {
+		responseSize := 0
+		for _, p := range packets {
+			responseSize += len(p)
+		}
+
+		if float64(responseSize) > float64(requestSize)*l.limits.maxAmplificationRatio {
+			atomic.AddUint64(&l.dropped, 1)
+			return false
+		}
+	}
+
+	if l.limits.global.rate <= 0 {
+		return true
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !l.global.take(l.limits.global, float64(len(packets)), now) {
+		atomic.AddUint64(&l.limited, 1)
+		return false
+	}
+
+	return true
+}
+
+// stats returns the number of requests which have not been answered due to query limits.
+func (l *queryLimiter) stats() QueryLimitStats {
+	return QueryLimitStats{
+		Limited: atomic.LoadUint64(&l.limited),
+		Dropped: atomic.LoadUint64(&l.dropped),
+	}
+}
+
+// get returns the bucket for key, creating it if required. If the maximum number of sources is tracked, the least
+// recently used source is evicted, which is equivalent to it being idle until its bucket refilled.
+func (sb *sourceBuckets) get(key string, now time.Time) *tokenBucket {
+	if e, ok := sb.buckets[key]; ok {
+		sb.lru.MoveToFront(e)
+		return &e.Value.(*sourceBucket).bucket
+	}
+
+	if len(sb.buckets) >= sb.maxTracked {
+		if oldest := sb.lru.Back(); oldest != nil {
+			sb.lru.Remove(oldest)
+			delete(sb.buckets, oldest.Value.(*sourceBucket).key)
+		}
+	}
+
+	b := &sourceBucket{
+		key:    key,
+		bucket: tokenBucket{tokens: float64(sb.limit.burst), last: now},
+	}
+	sb.buckets[key] = sb.lru.PushFront(b)
+
+	return &b.bucket
+}
+
+// purge removes any sources whose buckets have refilled to their burst size, as they are equivalent to a new bucket.
+func (sb *sourceBuckets) purge(now time.Time) {
+	for e := sb.lru.Front(); e != nil; {
+		next := e.Next()
+
+		b := e.Value.(*sourceBucket)
+		b.bucket.refill(sb.limit, now)
+		if b.bucket.tokens >= float64(sb.limit.burst) {
+			sb.lru.Remove(e)
+			delete(sb.buckets, b.key)
+		}
+
+		e = next
+	}
+}
+
+// subnet returns the subnet the address belongs to, based upon the configured prefix lengths.
+func (l *queryLimiter) subnet(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(l.limits.subnetPrefixIPv4, 32)).String()
+	}
+
+	return ip.Mask(net.CIDRMask(l.limits.subnetPrefixIPv6, 128)).String()
+}
+
+// purge removes any sources whose buckets have refilled, as they are equivalent to a new bucket. The caller must
+// hold mtx.
+func (l *queryLimiter) purge(now time.Time) {
+	l.lastPurged = now
+	l.perIP.purge(now)
+	l.perSubnet.purge(now)
+}
+
+// available refills the bucket and determines whether n tokens are available to take from it. A nil bucket, as used
+// for disabled limits, always has tokens available.
+func (b *tokenBucket) available(limit rateLimit, n float64, now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	b.refill(limit, now)
+	return b.tokens >= n
+}
+
+// take refills the bucket and takes n tokens from it, if available. Taking from a nil bucket always succeeds.
+func (b *tokenBucket) take(limit rateLimit, n float64, now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	b.refill(limit, now)
+
+	if b.tokens < n {
+		return false
+	}
+
+	b.tokens -= n
+	return true
+}
+
+// refill adds tokens to the bucket based upon the time elapsed since it was last refilled.
+func (b *tokenBucket) refill(limit rateLimit, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * limit.rate
+		if b.tokens > float64(limit.burst) {
+			b.tokens = float64(limit.burst)
+		}
+	}
+
+	b.last = now
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server/proto"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_queryLimiter_perIP(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{perIP: rateLimit{rate: 1, burst: 2}})
+	now := time.Now()
+	a := net.ParseIP("10.0.0.1")
+	b := net.ParseIP("10.0.0.2")
+
+	require.True(t, l.allowRequest(a, now))
+	require.True(t, l.allowRequest(a, now))
+	require.False(t, l.allowRequest(a, now))
+
+	// Other sources are unaffected.
+	require.True(t, l.allowRequest(b, now))
+
+	// Tokens refill over time.
+	require.True(t, l.allowRequest(a, now.Add(1*time.Second)))
+	require.False(t, l.allowRequest(a, now.Add(1*time.Second)))
+
+	require.Equal(t, QueryLimitStats{Limited: 2}, l.stats())
+}
+
+func Test_queryLimiter_perSubnet(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{perSubnet: rateLimit{rate: 1, burst: 1}})
+	now := time.Now()
+
+	require.True(t, l.allowRequest(net.ParseIP("10.0.0.1"), now))
+	require.False(t, l.allowRequest(net.ParseIP("10.0.0.2"), now))
+	require.True(t, l.allowRequest(net.ParseIP("10.0.1.1"), now))
+
+	require.True(t, l.allowRequest(net.ParseIP("2001:db8::1"), now))
+	require.False(t, l.allowRequest(net.ParseIP("2001:db8::2"), now))
+	require.True(t, l.allowRequest(net.ParseIP("2001:db8:0:1::1"), now))
+}
+
+func Test_queryLimiter_global(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{global: rateLimit{rate: 1, burst: 2}})
+	now := time.Now()
+
+	require.True(t, l.allowResponse(QueryProtocolSQP, 5, [][]byte{{0}, {0}}, false, now))
+	require.False(t, l.allowResponse(QueryProtocolSQP, 5, [][]byte{{0}}, false, now))
+	require.True(t, l.allowResponse(QueryProtocolSQP, 5, [][]byte{{0}}, false, now.Add(1*time.Second)))
+	require.Equal(t, QueryLimitStats{Limited: 1}, l.stats())
+}
+
+func Test_queryLimiter_amplification(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{maxAmplificationRatio: 2})
+	now := time.Now()
+
+	require.True(t, l.allowResponse(QueryProtocolSQP, 5, [][]byte{make([]byte, 10)}, false, now))
+	require.False(t, l.allowResponse(QueryProtocolSQP, 5, [][]byte{make([]byte, 6), make([]byte, 5)}, false, now))
+	require.True(t, l.allowResponse(QueryProtocolSQP, 5, [][]byte{make([]byte, 100)}, true, now))
+	require.Equal(t, QueryLimitStats{Dropped: 1}, l.stats())
+}
+
+func Test_queryLimiter_amplificationExempt(t *testing.T) {
+	t.Parallel()
+
+	r, err := newQueryResponder(QueryProtocolQ3, proto.NewQueryStatePointer(&proto.QueryState{
+		ServerName: "a server name longer than the request",
+		Map:        "a map name",
+	}))
+	require.NoError(t, err)
+
+	// Quake 3 has no challenge handshake, so its responses are dropped unless the protocol is exempted.
+	l := newQueryLimiter(queryLimits{maxAmplificationRatio: 1})
+	exempt := newQueryLimiter(queryLimits{
+		maxAmplificationRatio: 1,
+		amplificationExempt:   map[QueryProtocol]bool{QueryProtocolQ3: true},
+	})
+	now := time.Now()
+
+	for _, req := range []string{"\xFF\xFF\xFF\xFFgetstatus\n", "\xFF\xFF\xFF\xFFgetinfo\n"} {
+		resp, err := r.Respond("client-addr:1234", []byte(req))
+		require.NoError(t, err)
+		require.Greater(t, len(resp), len(req))
+		require.False(t, challengeSatisfied(r, "client-addr:1234"))
+
+		require.False(t, l.allowResponse(QueryProtocolQ3, len(req), [][]byte{resp}, false, now))
+		require.True(t, exempt.allowResponse(QueryProtocolQ3, len(req), [][]byte{resp}, false, now))
+
+		// Other protocols are still limited.
+		require.False(t, exempt.allowResponse(QueryProtocolSQP, len(req), [][]byte{resp}, false, now))
+	}
+
+	require.Equal(t, QueryLimitStats{Dropped: 2}, l.stats())
+	require.Equal(t, QueryLimitStats{Dropped: 2}, exempt.stats())
+}
+
+func Test_queryLimiter_purge(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{perIP: rateLimit{rate: 1, burst: 1}})
+	now := time.Now()
+
+	require.True(t, l.allowRequest(net.ParseIP("10.0.0.1"), now))
+	require.True(t, l.allowRequest(net.ParseIP("10.0.0.2"), now))
+	require.Len(t, l.perIP.buckets, 2)
+
+	// Idle sources are purged once their buckets have refilled.
+	require.True(t, l.allowRequest(net.ParseIP("10.0.0.2"), now.Add(l.purgePeriod+time.Second)))
+	require.Len(t, l.perIP.buckets, 1)
+	require.Equal(t, 1, l.perIP.lru.Len())
+}
+
+func Test_queryLimiter_evictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{perIP: rateLimit{rate: 1, burst: 1}})
+	l.perIP.maxTracked = 2
+	now := time.Now()
+	a := net.ParseIP("10.0.0.1")
+	b := net.ParseIP("10.0.0.2")
+	c := net.ParseIP("10.0.0.3")
+
+	require.True(t, l.allowRequest(a, now))
+	require.True(t, l.allowRequest(b, now))
+	require.False(t, l.allowRequest(a, now))
+
+	// New sources are not refused once the maximum number of sources is tracked. Instead, the least recently used
+	// source is evicted.
+	require.True(t, l.allowRequest(c, now))
+	require.Len(t, l.perIP.buckets, 2)
+	require.Contains(t, l.perIP.buckets, a.String())
+	require.NotContains(t, l.perIP.buckets, b.String())
+
+	// Recently used sources remain limited.
+	require.False(t, l.allowRequest(a, now))
+}
+
+func Test_queryLimiter_refusedByOneLimit(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{
+		perIP:     rateLimit{rate: 1, burst: 1},
+		perSubnet: rateLimit{rate: 1, burst: 1},
+	})
+	now := time.Now()
+
+	require.True(t, l.allowRequest(net.ParseIP("10.0.0.1"), now))
+
+	// The request is refused by the subnet limit, so no token is taken from the bucket of the address.
+	require.False(t, l.allowRequest(net.ParseIP("10.0.0.2"), now))
+	require.Equal(t, float64(1), l.perIP.get("10.0.0.2", now).tokens)
+	require.Equal(t, QueryLimitStats{Limited: 1}, l.stats())
+}
+
+func Test_queryLimiter_disabled(t *testing.T) {
+	t.Parallel()
+
+	l := newQueryLimiter(queryLimits{})
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		require.True(t, l.allowRequest(net.ParseIP("10.0.0.1"), now))
+		require.True(t, l.allowResponse(QueryProtocolSQP, 1, [][]byte{make([]byte, 1000)}, false, now))
+	}
+
+	require.Empty(t, l.perIP.buckets)
+	require.Equal(t, QueryLimitStats{}, l.stats())
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package server
+
+import (
+	"syscall"
+)
+
+// reusePortControl returns ErrReusePortUnsupported, as SO_REUSEPORT is only supported on Linux.
+func reusePortControl(_, _ string, _ syscall.RawConn) error {
+	return ErrReusePortUnsupported
+}
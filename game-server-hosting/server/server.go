@@ -2,12 +2,14 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -30,6 +32,18 @@ type (
 		// cfgFile is the file path this game uses to read its configuration from
 		cfgFile string
 
+		// cfgHash is the hash of the contents of the configuration file last read, so that rewrites with identical
+		// contents are ignored.
+		cfgHash [sha256.Size]byte
+
+		// cfgReloads counts the times the watcher has reloaded the configuration file, whether or not it changed.
+		cfgReloads atomic.Uint64
+
+		// Configuration file watching
+		cfgDebounceDuration time.Duration
+		cfgPollInterval     time.Duration
+		cfgPolling          bool
+
 		// internalEventProcessorReady is a channel that, when written to,
 		// indicates that the internal event processor is ready.
 		internalEventProcessorReady chan struct{}
@@ -37,36 +51,55 @@ type (
 		// eventWatcherReady is the channel that, when written to, indicates that the event watcher is ready.
 		eventWatcherReady chan error
 
-		// queryBind is a UDP endpoint which responds to game queries
-		queryBind *udpBinding
+		// queryEndpoint holds the UDP sockets which respond to game queries. There is more than one socket only if
+		// port reuse is enabled.
+		queryEndpoint *queryEndpoint
 
 		// queryProto is an implementation of an interface which responds on a particular
-		// query format, for example sqp, tf2e, etc.
-		queryProto proto.QueryResponder
+		// query format, for example sqp, tf2e, etc. It is replaced if the query type changes.
+		queryProto atomic.Pointer[queryProtocolResponder]
+
+		// queryMtx serialises changes to the query endpoint, which can be switched by configuration changes.
+		queryMtx sync.Mutex
 
 		// serverType holds the type of server this instance is.
 		serverType Type
 
-		// state represents current game states which are applicable to an incoming query,
-		// for example current players, map name
-		state     proto.QueryState
+		// state holds the current snapshot of game states which are applicable to an incoming query, for example
+		// current players, map name. Snapshots are read by query responders without locking, so are never modified
+		// once stored. stateLock serialises updates.
+		state     atomic.Pointer[proto.QueryState]
 		stateLock sync.Mutex
 
 		// Event Channels
 		chanAllocated            chan string
 		chanConfigurationChanged chan Config
+		chanConfigChange         chan ConfigChange
 		chanDeallocated          chan string
 		chanError                chan error
 
 		// Configuration-related items
-		currentConfigMtx sync.RWMutex
-		currentConfig    Config
+		currentConfigMtx       sync.RWMutex
+		currentConfig          Config
+		configSubscriptions    []configSubscription
+		configSubscriptionsMtx sync.Mutex
 
 		// Query-related configuration
 		queryWriteBufferSizeBytes  int
 		queryWriteDeadlineDuration time.Duration
 		queryReadBufferSizeBytes   int
 		queryReadDeadlineDuration  time.Duration
+		queryStatelessChallenges   bool
+		queryBindMode              QueryBindMode
+		queryBindConfiguredAddress bool
+		queryLimits                queryLimits
+		queryLimiter               *queryLimiter
+		queryWorkers               int
+		queryReusePort             bool
+
+		// Named metrics, mapping names to metric indexes.
+		metricIndexes    map[string]byte
+		metricIndexesMtx sync.Mutex
 
 		// Local proxy
 		localProxyClient *localproxy.Client
@@ -97,6 +130,17 @@ const (
 
 	// DefaultReadBufferSizeBytes represents the default size of the read buffer for the query handler.
 	DefaultReadBufferSizeBytes = 1024
+
+	// DefaultQueryWorkers represents the default number of goroutines reading and responding to query requests.
+	DefaultQueryWorkers = 1
+
+	// DefaultConfigDebounceDuration represents the default time the configuration file must be left unchanged after
+	// a change is detected before it is read, so that a burst of writes results in a single change.
+	DefaultConfigDebounceDuration = 100 * time.Millisecond
+
+	// DefaultConfigPollInterval represents the default interval at which the configuration file is read when
+	// polling for changes.
+	DefaultConfigPollInterval = 1 * time.Second
 )
 
 var (
@@ -115,6 +159,18 @@ var (
 	// ErrMetricOutOfBounds represents that the metric index provided will overflow the metrics buffer.
 	ErrMetricOutOfBounds = errors.New("metrics index provided will overflow the metrics buffer")
 
+	// ErrMetricNameEmpty represents that the name of the metric being registered is empty.
+	ErrMetricNameEmpty = errors.New("metric name is empty")
+
+	// ErrMetricAlreadyRegistered represents that a metric with the name provided has already been registered.
+	ErrMetricAlreadyRegistered = errors.New("metric with this name is already registered")
+
+	// ErrMetricsExhausted represents that every metric index has been allocated to a named metric.
+	ErrMetricsExhausted = errors.New("all metric indexes are allocated")
+
+	// ErrUnsupportedRuleType represents that the type of the rule value provided cannot be reported by query protocols.
+	ErrUnsupportedRuleType = errors.New("rule value type is not supported")
+
 	/// ErrNotAllocated represents that the server is not allocated.
 	ErrNotAllocated = errors.New("server is not allocated")
 )
@@ -133,6 +189,7 @@ func New(serverType Type, opts ...Option) (*Server, error) {
 		chanDeallocated:             make(chan string, 1),
 		chanError:                   make(chan error, 1),
 		chanConfigurationChanged:    make(chan Config, 1),
+		chanConfigChange:            make(chan ConfigChange, 1),
 		internalEventProcessorReady: make(chan struct{}, 1),
 		eventWatcherReady:           make(chan error, 1),
 		done:                        make(chan struct{}, 1),
@@ -140,13 +197,20 @@ func New(serverType Type, opts ...Option) (*Server, error) {
 		queryWriteDeadlineDuration:  DefaultWriteDeadlineDuration,
 		queryReadBufferSizeBytes:    DefaultReadBufferSizeBytes,
 		queryReadDeadlineDuration:   DefaultReadDeadlineDuration,
+		queryWorkers:                DefaultQueryWorkers,
+		cfgDebounceDuration:         DefaultConfigDebounceDuration,
+		cfgPollInterval:             DefaultConfigPollInterval,
 	}
 
+	s.state.Store(&proto.QueryState{})
+
 	// Apply any specified options.
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	s.queryLimiter = newQueryLimiter(s.queryLimits)
+
 	return s, nil
 }
 
@@ -156,11 +220,21 @@ func New(serverType Type, opts ...Option) (*Server, error) {
 // As the server can start in an allocated state, make sure that another goroutine is consuming messages from at least
 // the `OnAllocated()` and `OnDeallocated()` channels before calling this method.
 func (s *Server) Start() error {
-	c, err := newConfigFromFile(s.cfgFile)
+	data, err := os.ReadFile(s.cfgFile)
+	if err != nil {
+		return fmt.Errorf("error reading configuration file: %w", err)
+	}
+
+	c, err := newConfigFromJSON(data)
 	if err != nil {
 		return err
 	}
 
+	if err = c.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	s.cfgHash = sha256.Sum256(data)
 	s.setConfig(c)
 
 	// Create the directory the logs will be present in.
@@ -174,16 +248,24 @@ func (s *Server) Start() error {
 	s.SetGameMap("go-sdk-map")
 
 	// Set up metrics buffer, if supported.
-	if c.QueryType == QueryProtocolSQP {
-		s.state.Metrics = make([]float32, 0, sqp.MaxMetrics)
+	if supportsMetrics(c.QueryType) {
+		s.updateState(func(qs *proto.QueryState) {
+			qs.Metrics = make([]float32, 0, sqp.MaxMetrics)
+		})
 	}
 
-	if err = s.switchQueryProtocol(*c); err != nil {
+	s.queryMtx.Lock()
+	err = s.switchQueryProtocol(*c)
+	s.queryMtx.Unlock()
+
+	if err != nil {
 		return err
 	}
 
 	port, _ := c.Port.Int64()
-	s.state.Port = uint16(port)
+	s.updateState(func(qs *proto.QueryState) {
+		qs.Port = uint16(port)
+	})
 
 	go s.watchForConfigChanges()
 	go s.listenForEvents()
@@ -213,9 +295,9 @@ func (s *Server) WaitUntilTerminated() error {
 
 // Stop stops the game, pushing a de-allocation message and closing the query port.
 func (s *Server) Stop() error {
-	if s.queryBind != nil {
-		s.queryBind.Close()
-	}
+	s.queryMtx.Lock()
+	s.closeQueryEndpoint()
+	s.queryMtx.Unlock()
 
 	// Publish a de-allocation message.
 	s.chanDeallocated <- ""
@@ -326,62 +408,355 @@ func (s *Server) ReadyForPlayers(ctx context.Context) error {
 
 // PlayerJoined indicates a new player has joined the server.
 func (s *Server) PlayerJoined() int32 {
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
-	s.state.CurrentPlayers++
-	return s.state.CurrentPlayers
+	var players int32
+	s.updateState(func(qs *proto.QueryState) {
+		qs.CurrentPlayers++
+		players = qs.CurrentPlayers
+	})
+
+	return players
 }
 
 // PlayerLeft indicates a player has left the server.
 func (s *Server) PlayerLeft() int32 {
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
-	if s.state.CurrentPlayers > 0 {
-		s.state.CurrentPlayers--
-	}
-	return s.state.CurrentPlayers
+	var players int32
+	s.updateState(func(qs *proto.QueryState) {
+		if qs.CurrentPlayers > 0 {
+			qs.CurrentPlayers--
+		}
+		players = qs.CurrentPlayers
+	})
+
+	return players
 }
 
 // SetCurrentPlayers sets the number of players currently in the game. Can be used as an alternative to PlayerJoined
 // and PlayerLeft.
 func (s *Server) SetCurrentPlayers(players int32) {
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
-
 	if players < 0 {
 		players = 0
 	}
 
-	s.state.CurrentPlayers = players
+	s.updateState(func(qs *proto.QueryState) {
+		qs.CurrentPlayers = players
+	})
 }
 
 // SetMaxPlayers sets the maximum players this server will host. It does not enforce this number,
 // it only serves for query / metrics.
 func (s *Server) SetMaxPlayers(max int32) {
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
-	s.state.MaxPlayers = max
+	s.updateState(func(qs *proto.QueryState) {
+		qs.MaxPlayers = max
+	})
 }
 
 // SetServerName sets the server name for query / metrics purposes.
 func (s *Server) SetServerName(name string) {
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
-	s.state.ServerName = name
+	s.updateState(func(qs *proto.QueryState) {
+		qs.ServerName = name
+	})
 }
 
 // SetGameType sets the server game type for query / metrics purposes.
 func (s *Server) SetGameType(gameType string) {
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
-	s.state.GameType = gameType
+	s.updateState(func(qs *proto.QueryState) {
+		qs.GameType = gameType
+	})
 }
 
 // SetGameMap sets the server game map for query / metrics purposes.
 func (s *Server) SetGameMap(gameMap string) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.Map = gameMap
+	})
+}
+
+// SetGameVersion sets the game version string for query / metrics purposes.
+func (s *Server) SetGameVersion(version string) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.Version = version
+	})
+}
+
+// SetBots sets the number of bots currently in the game for query / metrics purposes.
+func (s *Server) SetBots(bots int32) {
+	if bots < 0 {
+		bots = 0
+	}
+
+	s.updateState(func(qs *proto.QueryState) {
+		qs.Bots = bots
+	})
+}
+
+// SetPasswordProtected sets whether a password is required to join the game, for query purposes.
+func (s *Server) SetPasswordProtected(protected bool) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.PasswordProtected = protected
+	})
+}
+
+// SetKeywords sets the tags which describe the game, for query purposes. These are used by server browsers
+// to filter servers.
+func (s *Server) SetKeywords(keywords ...string) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.Keywords = append([]string(nil), keywords...)
+	})
+}
+
+// SetSteamID sets the Steam ID of the server, for query purposes. Only applicable to the A2S query protocol.
+func (s *Server) SetSteamID(id uint64) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.SteamID = id
+	})
+}
+
+// SetSourceTV sets the port and name of the spectator (SourceTV) endpoint of the server, for query purposes. A port
+// of 0 denotes there is no spectator endpoint. Only applicable to the A2S query protocol.
+func (s *Server) SetSourceTV(port uint16, name string) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.SourceTVPort = port
+		qs.SourceTVName = name
+	})
+}
+
+// SetGameID sets the 64-bit game ID of the server, for query purposes. Only applicable to the A2S query protocol.
+func (s *Server) SetGameID(id uint64) {
+	s.updateState(func(qs *proto.QueryState) {
+		qs.GameID = id
+	})
+}
+
+// SetPlayer adds a player to the list of players reported for query purposes, replacing any existing player with the
+// same ID. If JoinedAt is not set, the join time of the existing player is kept, or the current time is used for a new
+// player. The player list is independent of the player count maintained by PlayerJoined, PlayerLeft and
+// SetCurrentPlayers.
+func (s *Server) SetPlayer(player proto.QueryPlayer) {
+	s.updateState(func(qs *proto.QueryState) {
+		players := make([]proto.QueryPlayer, 0, len(qs.Players)+1)
+		replaced := false
+		for _, p := range qs.Players {
+			if p.ID == player.ID {
+				if player.JoinedAt.IsZero() {
+					player.JoinedAt = p.JoinedAt
+				}
+				p = player
+				replaced = true
+			}
+			players = append(players, p)
+		}
+
+		if !replaced {
+			if player.JoinedAt.IsZero() {
+				player.JoinedAt = time.Now()
+			}
+			players = append(players, player)
+		}
+
+		qs.Players = players
+	})
+}
+
+// RemovePlayer removes the player with the specified ID from the list of players reported for query purposes,
+// returning whether the player was present.
+func (s *Server) RemovePlayer(id string) bool {
+	removed := false
+	s.updateState(func(qs *proto.QueryState) {
+		players := make([]proto.QueryPlayer, 0, len(qs.Players))
+		for _, p := range qs.Players {
+			if p.ID != id {
+				players = append(players, p)
+			}
+		}
+
+		removed = len(players) != len(qs.Players)
+		qs.Players = players
+	})
+
+	return removed
+}
+
+// Players returns a copy of the list of players reported for query purposes.
+func (s *Server) Players() []proto.QueryPlayer {
+	qs := s.state.Load()
+	players := make([]proto.QueryPlayer, len(qs.Players))
+	copy(players, qs.Players)
+	return players
+}
+
+// SetRule sets a named server rule for query purposes, for example the game mode or current round. Supported value
+// types are bool, the integer and floating point types and string, otherwise ErrUnsupportedRuleType is returned.
+func (s *Server) SetRule(key string, value any) error {
+	if err := checkRuleValue(key, value); err != nil {
+		return err
+	}
+
+	s.updateState(func(qs *proto.QueryState) {
+		rules := make(map[string]any, len(qs.Rules)+1)
+		for k, v := range qs.Rules {
+			rules[k] = v
+		}
+
+		rules[key] = value
+		qs.Rules = rules
+	})
+
+	return nil
+}
+
+// DeleteRule removes a named server rule, returning whether the rule was present.
+func (s *Server) DeleteRule(key string) bool {
+	removed := false
+	s.updateState(func(qs *proto.QueryState) {
+		rules := make(map[string]any, len(qs.Rules))
+		for k, v := range qs.Rules {
+			if k != key {
+				rules[k] = v
+			}
+		}
+
+		removed = len(rules) != len(qs.Rules)
+		qs.Rules = rules
+	})
+
+	return removed
+}
+
+// SetRules replaces all named server rules with the provided set. Supported value types are as per SetRule, and
+// the rules are left unchanged if any value is unsupported.
+func (s *Server) SetRules(rules map[string]any) error {
+	for k, v := range rules {
+		if err := checkRuleValue(k, v); err != nil {
+			return err
+		}
+	}
+
+	s.updateState(func(qs *proto.QueryState) {
+		qs.Rules = make(map[string]any, len(rules))
+		for k, v := range rules {
+			qs.Rules[k] = v
+		}
+	})
+
+	return nil
+}
+
+// checkRuleValue determines whether the value of the named rule can be reported by query protocols.
+func checkRuleValue(key string, value any) error {
+	switch value.(type) {
+	case bool, string,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return nil
+	}
+
+	return fmt.Errorf("rule %s: %w: %T", key, ErrUnsupportedRuleType, value)
+}
+
+// Rules returns a copy of the named server rules.
+func (s *Server) Rules() map[string]any {
+	qs := s.state.Load()
+	rules := make(map[string]any, len(qs.Rules))
+	for k, v := range qs.Rules {
+		rules[k] = v
+	}
+
+	return rules
+}
+
+// SetTeam adds a team to the list of teams reported for query purposes, replacing any existing team with the
+// same name.
+func (s *Server) SetTeam(team proto.QueryTeam) {
+	s.updateState(func(qs *proto.QueryState) {
+		setTeam(qs, team.Name, func(proto.QueryTeam) proto.QueryTeam {
+			return team
+		})
+	})
+}
+
+// SetTeamScore sets the score of the named team, adding the team if it is not already present.
+func (s *Server) SetTeamScore(name string, score int32) {
+	s.updateState(func(qs *proto.QueryState) {
+		setTeam(qs, name, func(t proto.QueryTeam) proto.QueryTeam {
+			t.Score = score
+			return t
+		})
+	})
+}
+
+// AddTeamScore adds delta to the score of the named team, adding the team if it is not already present. The new
+// score is returned.
+func (s *Server) AddTeamScore(name string, delta int32) int32 {
+	var score int32
+	s.updateState(func(qs *proto.QueryState) {
+		setTeam(qs, name, func(t proto.QueryTeam) proto.QueryTeam {
+			t.Score += delta
+			score = t.Score
+			return t
+		})
+	})
+
+	return score
+}
+
+// RemoveTeam removes the named team from the list of teams reported for query purposes, returning whether the team
+// was present.
+func (s *Server) RemoveTeam(name string) bool {
+	removed := false
+	s.updateState(func(qs *proto.QueryState) {
+		teams := make([]proto.QueryTeam, 0, len(qs.Teams))
+		for _, t := range qs.Teams {
+			if t.Name != name {
+				teams = append(teams, t)
+			}
+		}
+
+		removed = len(teams) != len(qs.Teams)
+		qs.Teams = teams
+	})
+
+	return removed
+}
+
+// Teams returns a copy of the list of teams reported for query purposes.
+func (s *Server) Teams() []proto.QueryTeam {
+	qs := s.state.Load()
+	teams := make([]proto.QueryTeam, len(qs.Teams))
+	copy(teams, qs.Teams)
+	return teams
+}
+
+// updateState publishes a new snapshot of the query state, which is a copy of the current snapshot modified by
+// update with its revision incremented. Snapshots are read by query responders without locking, so update must
+// replace, rather than modify, any slices or maps it changes.
+func (s *Server) updateState(update func(qs *proto.QueryState)) {
 	s.stateLock.Lock()
 	defer s.stateLock.Unlock()
-	s.state.Map = gameMap
+
+	next := *s.state.Load()
+	update(&next)
+	next.Revision++
+	s.state.Store(&next)
+}
+
+// setTeam replaces the named team in qs with the result of update, adding a new team if one is not already present.
+func setTeam(qs *proto.QueryState, name string, update func(proto.QueryTeam) proto.QueryTeam) {
+	teams := make([]proto.QueryTeam, 0, len(qs.Teams)+1)
+	found := false
+	for _, t := range qs.Teams {
+		if t.Name == name {
+			t = update(t)
+			found = true
+		}
+		teams = append(teams, t)
+	}
+
+	if !found {
+		teams = append(teams, update(proto.QueryTeam{Name: name}))
+	}
+
+	qs.Teams = teams
 }
 
 // Config returns a copy of the configuration the server is currently using.
@@ -391,33 +766,59 @@ func (s *Server) Config() Config {
 	return s.currentConfig
 }
 
-// SetMetric sets the metric at index to provided value. Only supported if the query type is QueryProtocolSQP, otherwise,
-// ErrMetricsUnsupported is returned. The maximum index is specified by sqp.MaxMetrics, any index supplied above this
-// will return ErrMetricOutOfBounds.
+// SetMetric sets the metric at index to provided value. Only supported if the query type is QueryProtocolSQP or
+// QueryProtocolSQPAndA2S; otherwise, ErrMetricsUnsupported is returned. The maximum index is specified by
+// sqp.MaxMetrics. Any index supplied above this will return ErrMetricOutOfBounds.
 func (s *Server) SetMetric(index byte, value float32) error {
-	if s.Config().QueryType != QueryProtocolSQP {
-		return ErrMetricsUnsupported
-	}
+	_, err := s.updateMetric(index, func(float32) float32 {
+		return value
+	})
 
-	s.stateLock.Lock()
-	defer s.stateLock.Unlock()
+	return err
+}
 
-	if index >= sqp.MaxMetrics {
-		return ErrMetricOutOfBounds
+// updateMetric replaces the metric at index with the result of update, returning the new value. Errors are as per
+// SetMetric.
+func (s *Server) updateMetric(index byte, update func(value float32) float32) (float32, error) {
+	// The running query protocol is checked rather than the configuration, as configurations which only carry
+	// changes, such as those written on deallocation, default the query type.
+	if qp := s.queryResponder(); qp == nil || !supportsMetrics(qp.name) {
+		return 0, ErrMetricsUnsupported
 	}
 
-	// Expand slice to fit new index if needed.
-	if int(index) >= len(s.state.Metrics) {
-		s.state.Metrics = s.state.Metrics[:index+1]
+	if index >= sqp.MaxMetrics {
+		return 0, ErrMetricOutOfBounds
 	}
 
-	s.state.Metrics[index] = value
-	return nil
+	var value float32
+	s.updateState(func(qs *proto.QueryState) {
+		// Expand slice to fit new index if needed.
+		n := len(qs.Metrics)
+		if int(index) >= n {
+			n = int(index) + 1
+		}
+
+		metrics := make([]float32, n, sqp.MaxMetrics)
+		copy(metrics, qs.Metrics)
+		metrics[index] = update(metrics[index])
+		value = metrics[index]
+		qs.Metrics = metrics
+	})
+
+	return value, nil
 }
 
-// setConfig sets the configuration the server is currently using.
-func (s *Server) setConfig(c *Config) {
+// QueryLimitStats returns the number of query requests which have not been answered due to the configured query
+// rate limits and amplification protection.
+func (s *Server) QueryLimitStats() QueryLimitStats {
+	return s.queryLimiter.stats()
+}
+
+// setConfig sets the configuration the server is currently using, returning the change from the previous
+// configuration.
+func (s *Server) setConfig(c *Config) ConfigChange {
 	s.currentConfigMtx.Lock()
+	change := newConfigChange(s.currentConfig, *c)
 	s.currentConfig = *c
 	s.currentConfigMtx.Unlock()
 
@@ -427,4 +828,8 @@ func (s *Server) setConfig(c *Config) {
 	case s.chanConfigurationChanged <- *c:
 	default:
 	}
+
+	s.publishConfigChange(change)
+
+	return change
 }
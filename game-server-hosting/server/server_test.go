@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -71,8 +72,8 @@ func Test_StartStopQuery(t *testing.T) {
 
 	// Make sure query parameters have been set
 	s.stateLock.Lock()
-	require.Equal(t, "go-sdk-server - 1234", s.state.ServerName)
-	require.Equal(t, "go-sdk-map", s.state.Map)
+	require.Equal(t, "go-sdk-server - 1234", s.state.Load().ServerName)
+	require.Equal(t, "go-sdk-map", s.state.Load().Map)
 	s.stateLock.Unlock()
 
 	// Check query port is open on SQP (check that we receive an SQP challenge response)
@@ -95,6 +96,24 @@ func Test_StartStopQuery(t *testing.T) {
 	require.Len(t, s.OnError(), 0)
 }
 
+func Test_Start_invalidConfig(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "server.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"queryPort": "9010",
+		"serverID": "1.5",
+		"serverLogDir": "/logs"
+	}`), 0o600))
+
+	s, err := New(TypeAllocation, WithConfigPath(path))
+	require.NoError(t, err)
+
+	err = s.Start()
+	require.ErrorIs(t, err, ErrConfigFieldNotNumeric)
+	require.Equal(t, Config{}, s.Config())
+}
+
 func Test_OnAllocate(t *testing.T) {
 	t.Parallel()
 
@@ -159,16 +178,16 @@ func Test_PlayerJoinedAndLeft(t *testing.T) {
 
 	i := s.PlayerJoined()
 	require.Equal(t, int32(1), i)
-	require.Equal(t, int32(1), s.state.CurrentPlayers)
+	require.Equal(t, int32(1), s.state.Load().CurrentPlayers)
 
 	i = s.PlayerLeft()
 	require.Equal(t, int32(0), i)
-	require.Equal(t, int32(0), s.state.CurrentPlayers)
+	require.Equal(t, int32(0), s.state.Load().CurrentPlayers)
 
 	// Make sure we do not underflow.
 	i = s.PlayerLeft()
 	require.Equal(t, int32(0), i)
-	require.Equal(t, int32(0), s.state.CurrentPlayers)
+	require.Equal(t, int32(0), s.state.Load().CurrentPlayers)
 }
 
 func Test_SetCurrentPlayers(t *testing.T) {
@@ -178,10 +197,10 @@ func Test_SetCurrentPlayers(t *testing.T) {
 	require.NoError(t, err)
 
 	s.SetCurrentPlayers(10)
-	require.Equal(t, int32(10), s.state.CurrentPlayers)
+	require.Equal(t, int32(10), s.state.Load().CurrentPlayers)
 
 	s.SetCurrentPlayers(-1)
-	require.Equal(t, int32(0), s.state.CurrentPlayers)
+	require.Equal(t, int32(0), s.state.Load().CurrentPlayers)
 }
 
 func Test_DataSettings(t *testing.T) {
@@ -194,13 +213,157 @@ func Test_DataSettings(t *testing.T) {
 	s.SetServerName("foo")
 	s.SetGameType("type")
 	s.SetGameMap("map")
+	s.SetGameVersion("1.0.0")
+	s.SetBots(2)
+	s.SetPasswordProtected(true)
+	s.SetKeywords("ctf", "casual")
+	s.SetSteamID(1)
+	s.SetSourceTV(27020, "tv")
+	s.SetGameID(730)
 
 	require.Equal(t, proto.QueryState{
-		MaxPlayers: 10,
-		ServerName: "foo",
-		GameType:   "type",
-		Map:        "map",
-	}, s.state)
+		MaxPlayers:        10,
+		ServerName:        "foo",
+		GameType:          "type",
+		Map:               "map",
+		Version:           "1.0.0",
+		Bots:              2,
+		PasswordProtected: true,
+		Keywords:          []string{"ctf", "casual"},
+		SteamID:           1,
+		SourceTVPort:      27020,
+		SourceTVName:      "tv",
+		GameID:            730,
+		Revision:          11,
+	}, *s.state.Load())
+}
+
+func Test_SetPlayer_RemovePlayer(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+
+	joinedAt := time.Now().Add(-1 * time.Minute)
+	s.SetPlayer(proto.QueryPlayer{ID: "a", Name: "foo", JoinedAt: joinedAt})
+	s.SetPlayer(proto.QueryPlayer{ID: "b", Name: "bar"})
+	s.SetPlayer(proto.QueryPlayer{ID: "a", Name: "baz", Score: 10})
+
+	players := s.Players()
+	require.Len(t, players, 2)
+	require.Equal(t, proto.QueryPlayer{ID: "a", Name: "baz", Score: 10, JoinedAt: joinedAt}, players[0])
+	require.Equal(t, "bar", players[1].Name)
+	require.False(t, players[1].JoinedAt.IsZero())
+
+	require.True(t, s.RemovePlayer("a"))
+	require.False(t, s.RemovePlayer("a"))
+	require.Equal(t, []proto.QueryPlayer{players[1]}, s.Players())
+
+	// The player count is maintained separately.
+	require.Equal(t, int32(0), s.state.Load().CurrentPlayers)
+}
+
+func Test_SetRule_DeleteRule(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetRule("mode", "ctf"))
+	require.NoError(t, s.SetRule("round", uint8(2)))
+	require.Equal(t, map[string]any{"mode": "ctf", "round": uint8(2)}, s.state.Load().Rules)
+
+	require.True(t, s.DeleteRule("mode"))
+	require.False(t, s.DeleteRule("mode"))
+	require.Equal(t, map[string]any{"round": uint8(2)}, s.Rules())
+
+	require.NoError(t, s.SetRules(map[string]any{"sv_cheats": false}))
+	require.Equal(t, map[string]any{"sv_cheats": false}, s.Rules())
+}
+
+func Test_SetRule_float(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetRule("gravity", 9.8))
+
+	// Floating point rules do not prevent SQP responses, which have no floating point type.
+	r, err := newQueryResponder(QueryProtocolSQP, &s.state)
+	require.NoError(t, err)
+
+	challenge, err := r.Respond("client-addr:1234", []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+
+	resp, err := r.Respond("client-addr:1234", append(append([]byte{1}, challenge[1:5]...), 0, 1, 0b00011111))
+	require.NoError(t, err)
+	require.Contains(t, string(resp), "gravity")
+
+	// Values which cannot be reported are rejected, leaving the rules unchanged.
+	require.ErrorIs(t, s.SetRule("spawn", struct{ X, Y float64 }{}), ErrUnsupportedRuleType)
+	require.ErrorIs(t, s.SetRules(map[string]any{"spawn": []int{1, 2}}), ErrUnsupportedRuleType)
+	require.Equal(t, map[string]any{"gravity": 9.8}, s.Rules())
+}
+
+func Test_Teams(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+
+	s.SetTeam(proto.QueryTeam{Name: "red", Score: 1})
+	s.SetTeamScore("blue", 2)
+	s.SetTeamScore("red", 3)
+	require.Equal(t, int32(5), s.AddTeamScore("blue", 3))
+	require.Equal(t, []proto.QueryTeam{
+		{Name: "red", Score: 3},
+		{Name: "blue", Score: 5},
+	}, s.Teams())
+
+	require.True(t, s.RemoveTeam("red"))
+	require.False(t, s.RemoveTeam("red"))
+	require.Equal(t, []proto.QueryTeam{{Name: "blue", Score: 5}}, s.Teams())
+}
+
+func Test_StateSnapshots(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(TypeAllocation)
+	require.NoError(t, err)
+
+	r, err := newQueryResponder(QueryProtocolQ3, &s.state)
+	require.NoError(t, err)
+
+	// Responders read snapshots while they are being replaced, which must not race.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				_, err := r.Respond("client-addr:1234", []byte("\xFF\xFF\xFF\xFFgetstatus\n"))
+				require.NoError(t, err)
+			}
+		}()
+	}
+
+	for j := 0; j < 200; j++ {
+		s.SetServerName(fmt.Sprintf("server-%d", j))
+		s.SetGameMap(fmt.Sprintf("map-%d", j))
+		s.SetPlayer(proto.QueryPlayer{ID: fmt.Sprint(j % 10), Name: "foo"})
+		require.NoError(t, s.SetRule("round", j))
+		s.PlayerJoined()
+	}
+
+	wg.Wait()
+
+	// Snapshots taken before an update are unaffected by it.
+	before := s.state.Load()
+	s.SetServerName("foo")
+	require.Equal(t, "server-199", before.ServerName)
+	require.Equal(t, "foo", s.state.Load().ServerName)
+	require.Equal(t, int32(200), s.state.Load().CurrentPlayers)
 }
 
 func Test_New_appliesOptions(t *testing.T) {
@@ -382,32 +545,22 @@ func Test_SetMetric(t *testing.T) {
 
 	// Add metric to the first index.
 	require.NoError(t, s.SetMetric(0, 1.234))
-	s.stateLock.Lock()
-	require.Equal(t, []float32{1.234}, s.state.Metrics)
-	s.stateLock.Unlock()
+	require.Equal(t, []float32{1.234}, s.state.Load().Metrics)
 
 	// Add metric to the last index - make sure all indices in between are set to the default values.
 	require.NoError(t, s.SetMetric(sqp.MaxMetrics-1, 5.678))
-	s.stateLock.Lock()
-	require.Equal(t, []float32{1.234, 0, 0, 0, 0, 0, 0, 0, 0, 5.678}, s.state.Metrics)
-	s.stateLock.Unlock()
+	require.Equal(t, []float32{1.234, 0, 0, 0, 0, 0, 0, 0, 0, 5.678}, s.state.Load().Metrics)
 
 	// Add a metric somewhere in the middle.
 	require.NoError(t, s.SetMetric(4, 9.012))
-	s.stateLock.Lock()
-	require.Equal(t, []float32{1.234, 0, 0, 0, 9.012, 0, 0, 0, 0, 5.678}, s.state.Metrics)
-	s.stateLock.Unlock()
+	require.Equal(t, []float32{1.234, 0, 0, 0, 9.012, 0, 0, 0, 0, 5.678}, s.state.Load().Metrics)
 
 	// Attempt to add metric out of bounds - an error should be returned and no change observed to the underlying buffer.
 	require.ErrorIs(t, s.SetMetric(sqp.MaxMetrics, 0.123), ErrMetricOutOfBounds)
-	s.stateLock.Lock()
-	require.Equal(t, []float32{1.234, 0, 0, 0, 9.012, 0, 0, 0, 0, 5.678}, s.state.Metrics)
-	s.stateLock.Unlock()
+	require.Equal(t, []float32{1.234, 0, 0, 0, 9.012, 0, 0, 0, 0, 5.678}, s.state.Load().Metrics)
 
 	// Attempt to set metrics on A2S - this should fail as this is currently unsupported.
-	s.currentConfigMtx.Lock()
-	s.currentConfig.QueryType = QueryProtocolA2S
-	s.currentConfigMtx.Unlock()
+	require.NoError(t, switchQuery(s, QueryProtocolA2S, queryEndpoint))
 	require.ErrorIs(t, s.SetMetric(0, 1.234), ErrMetricsUnsupported)
 
 	require.NoError(t, s.Stop())
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"time"
 
 	gsh "github.com/Unity-Technologies/unity-gaming-services-go-sdk/game-server-hosting/server"
@@ -168,16 +167,11 @@ func (s *Server) getJwtToken(c *gsh.Config) (string, error) {
 
 // backfillEnabled returns a boolean representation of the `enableBackfill` configuration item.
 func backfillEnabled(c gsh.Config) bool {
-	b, _ := strconv.ParseBool(c.Extra["enableBackfill"])
+	b, _ := c.ExtraBool("enableBackfill", false)
 	return b
 }
 
 // matchmakerURL returns the matchmaker URL, or a default if empty.
 func matchmakerURL(c gsh.Config) string {
-	u := c.Extra["matchmakerUrl"]
-	if u == "" {
-		return "https://matchmaker.services.api.unity.com"
-	}
-
-	return u
+	return c.ExtraString("matchmakerUrl", "https://matchmaker.services.api.unity.com")
 }